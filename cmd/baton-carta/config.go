@@ -3,15 +3,47 @@ package main
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/ConductorOne/baton-carta/pkg/carta"
 	"github.com/conductorone/baton-sdk/pkg/cli"
 	"github.com/spf13/cobra"
 )
 
 // config defines the external configuration required for the connector to run.
 type config struct {
-	cli.BaseConfig `mapstructure:",squash"` // Puts the base config options in the same place as the connector options
-	AccessToken    string                   `mapstructure:"token"`
+	cli.BaseConfig              `mapstructure:",squash"` // Puts the base config options in the same place as the connector options
+	AccessToken                 string                   `mapstructure:"token"`
+	BaseURL                     string                   `mapstructure:"base-url"`
+	UpdatedSince                string                   `mapstructure:"updated-since"`
+	InsecureSkipVerify          bool                     `mapstructure:"insecure-skip-verify"`
+	DebugHeaders                []string                 `mapstructure:"debug-headers"`
+	ExtraQueryParams            map[string]string        `mapstructure:"extra-query-params"`
+	ContinueOnPageError         bool                     `mapstructure:"continue-on-page-error"`
+	LazyIssuers                 bool                     `mapstructure:"lazy-issuers"`
+	SplitFunds                  bool                     `mapstructure:"split-funds"`
+	StakeholderWarningThreshold int                      `mapstructure:"stakeholder-warning-threshold"`
+	AcceptHeader                string                   `mapstructure:"accept-header"`
+	AdaptivePageSize            bool                     `mapstructure:"adaptive-page-size"`
+	StartPageTokens             map[string]string        `mapstructure:"start-page-tokens"`
+	MaxConcurrentRequests       int                      `mapstructure:"max-concurrent-requests"`
+	DetectPaginationDrift       bool                     `mapstructure:"detect-pagination-drift"`
+	ResourceIdPrefix            string                   `mapstructure:"resource-id-prefix"`
+	RequestTimeout              time.Duration            `mapstructure:"request-timeout"`
+	OperationTimeouts           map[string]string        `mapstructure:"operation-timeouts"`
+	MaxPortfolioIssuerPages     int                      `mapstructure:"max-portfolio-issuer-pages"`
+	RetryOnTimeout              bool                     `mapstructure:"retry-on-timeout"`
+	CircuitBreakerThreshold     int                      `mapstructure:"circuit-breaker-threshold"`
+	CircuitBreakerCooldown      time.Duration            `mapstructure:"circuit-breaker-cooldown"`
+	PortfolioNamePrefix         string                   `mapstructure:"portfolio-name-prefix"`
+	MaxPageSize                 int                      `mapstructure:"max-page-size"`
+	ResourcePageSizes           map[string]string        `mapstructure:"resource-page-sizes"`
+	DisplayNameTemplate         string                   `mapstructure:"display-name-template"`
+	SecurityCounts              bool                     `mapstructure:"security-counts"`
+	LogLevels                   map[string]string        `mapstructure:"log-levels"`
+	MaxResources                int                      `mapstructure:"max-resources"`
+	SkipEmptyPortfolios         bool                     `mapstructure:"skip-empty-portfolios"`
 }
 
 // validateConfig is run after the configuration is loaded, and should return an error if it isn't valid.
@@ -20,10 +52,119 @@ func validateConfig(ctx context.Context, cfg *config) error {
 		return fmt.Errorf("access token is missing")
 	}
 
+	if cfg.StakeholderWarningThreshold < 0 {
+		return fmt.Errorf("stakeholder-warning-threshold must not be negative")
+	}
+
+	if cfg.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("max-concurrent-requests must not be negative")
+	}
+
+	if cfg.MaxPageSize < 0 {
+		return fmt.Errorf("max-page-size must not be negative")
+	}
+
+	if cfg.MaxResources < 0 {
+		return fmt.Errorf("max-resources must not be negative")
+	}
+
+	if cfg.MaxPortfolioIssuerPages < 0 {
+		return fmt.Errorf("max-portfolio-issuer-pages must not be negative")
+	}
+
+	if cfg.RequestTimeout < 0 {
+		return fmt.Errorf("request-timeout must not be negative")
+	}
+
+	if cfg.CircuitBreakerThreshold < 0 {
+		return fmt.Errorf("circuit-breaker-threshold must not be negative")
+	}
+
+	if cfg.CircuitBreakerCooldown < 0 {
+		return fmt.Errorf("circuit-breaker-cooldown must not be negative")
+	}
+
+	if _, err := parseOperationTimeouts(cfg.OperationTimeouts); err != nil {
+		return err
+	}
+
+	if _, err := parseResourcePageSizes(cfg.ResourcePageSizes); err != nil {
+		return err
+	}
+
+	if _, err := carta.ParseLogLevels(cfg.LogLevels); err != nil {
+		return fmt.Errorf("log-levels: %w", err)
+	}
+
+	if cfg.UpdatedSince != "" {
+		if _, err := time.Parse(time.RFC3339, cfg.UpdatedSince); err != nil {
+			return fmt.Errorf("updated-since must be an RFC3339 timestamp: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// parseOperationTimeouts parses the per operation timeout overrides.
+func parseOperationTimeouts(raw map[string]string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(raw))
+	for operation, value := range raw {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("operation-timeouts: invalid timeout %q for %s", value, operation)
+		}
+
+		timeouts[operation] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// parseResourcePageSizes parses the per resource type page sizes.
+func parseResourcePageSizes(raw map[string]string) (map[string]int, error) {
+	pageSizes := make(map[string]int, len(raw))
+	for resourceTypeID, value := range raw {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil || pageSize <= 0 {
+			return nil, fmt.Errorf("resource-page-sizes: invalid page size %q for %s", value, resourceTypeID)
+		}
+
+		pageSizes[resourceTypeID] = pageSize
+	}
+
+	return pageSizes, nil
+}
+
 // cmdFlags sets the cmdFlags required for the connector.
 func cmdFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("token", "", "The Carta personal access token used to connect to the Carta API. ($BATON_TOKEN)")
+	cmd.PersistentFlags().String("base-url", carta.BaseURL, "The Carta API base URL, e.g. of a staging or mock API. ($BATON_BASE_URL)")
+	cmd.PersistentFlags().String("updated-since", "", "Only sync issuers and investors changed after this RFC3339 timestamp, omit for a full sync. ($BATON_UPDATED_SINCE)")
+	cmd.PersistentFlags().Bool("insecure-skip-verify", false, "INSECURE: skip TLS certificate verification, only for testing against local or staging mocks. ($BATON_INSECURE_SKIP_VERIFY)")
+	cmd.PersistentFlags().StringSlice("debug-headers", nil, "Request headers to log at debug level for troubleshooting, authorization is never logged. ($BATON_DEBUG_HEADERS)")
+	cmd.PersistentFlags().StringToString("extra-query-params", nil, "Additional query parameters sent with every Carta request, e.g. key=value. ($BATON_EXTRA_QUERY_PARAMS)")
+	cmd.PersistentFlags().Bool("continue-on-page-error", false, "Keep already synced pages and log the failed page token instead of failing the sync on a page fetch error. ($BATON_CONTINUE_ON_PAGE_ERROR)")
+	cmd.PersistentFlags().Bool("lazy-issuers", false, "Skip listing all issuers and only resolve issuers that are members of portfolios. ($BATON_LAZY_ISSUERS)")
+	cmd.PersistentFlags().Bool("split-funds", false, "Sync fund issuers under a separate fund resource type. ($BATON_SPLIT_FUNDS)")
+	cmd.PersistentFlags().Int("stakeholder-warning-threshold", 10000, "Warn about issuers with more stakeholders than this, 0 disables the warning. ($BATON_STAKEHOLDER_WARNING_THRESHOLD)")
+	cmd.PersistentFlags().String("accept-header", "application/json", "The accept header sent to the Carta API. ($BATON_ACCEPT_HEADER)")
+	cmd.PersistentFlags().Bool("adaptive-page-size", false, "Adjust the page size of listings based on observed response latency and errors. ($BATON_ADAPTIVE_PAGE_SIZE)")
+	cmd.PersistentFlags().StringToString("start-page-tokens", nil, "Carta page tokens to resume listings from, keyed by resource type, e.g. issuer=<token>. ($BATON_START_PAGE_TOKENS)")
+	cmd.PersistentFlags().Int("max-concurrent-requests", 0, "Maximum number of requests in flight to Carta at the same time, 0 means unlimited. ($BATON_MAX_CONCURRENT_REQUESTS)")
+	cmd.PersistentFlags().Bool("detect-pagination-drift", false, "Warn when the total count of a listing changes while its pages are synced. ($BATON_DETECT_PAGINATION_DRIFT)")
+	cmd.PersistentFlags().String("resource-id-prefix", "", "Prefix prepended to every resource id, to tell apart connector instances syncing the same Carta data. ($BATON_RESOURCE_ID_PREFIX)")
+	cmd.PersistentFlags().Duration("request-timeout", 0, "Timeout of a single request to Carta, 0 disables the timeout. ($BATON_REQUEST_TIMEOUT)")
+	cmd.PersistentFlags().StringToString("operation-timeouts", nil, "Request timeouts overriding request-timeout for specific operations, e.g. GetIssuersForPortfolio=2m. ($BATON_OPERATION_TIMEOUTS)")
+	cmd.PersistentFlags().Int("max-portfolio-issuer-pages", 0, "Maximum number of issuer pages fetched per portfolio, 0 fetches all pages. ($BATON_MAX_PORTFOLIO_ISSUER_PAGES)")
+	cmd.PersistentFlags().Bool("retry-on-timeout", true, "Retry requests to Carta that timed out. ($BATON_RETRY_ON_TIMEOUT)")
+	cmd.PersistentFlags().Int("circuit-breaker-threshold", carta.DefaultCircuitBreakerThreshold, "Consecutive failed requests after which requests to Carta are paused for the cooldown, 0 disables the circuit breaker. ($BATON_CIRCUIT_BREAKER_THRESHOLD)")
+	cmd.PersistentFlags().Duration("circuit-breaker-cooldown", carta.DefaultCircuitBreakerCooldown, "How long requests to Carta are paused once the circuit breaker opened. ($BATON_CIRCUIT_BREAKER_COOLDOWN)")
+	cmd.PersistentFlags().Bool("skip-empty-portfolios", false, "Don't sync portfolios without issuers nor investor firms. ($BATON_SKIP_EMPTY_PORTFOLIOS)")
+	cmd.PersistentFlags().String("portfolio-name-prefix", "", "Only sync portfolios whose name starts with this prefix. ($BATON_PORTFOLIO_NAME_PREFIX)")
+	cmd.PersistentFlags().Int("max-page-size", 0, "Page size cap used when Carta doesn't report its own limit, 0 means no cap. ($BATON_MAX_PAGE_SIZE)")
+	cmd.PersistentFlags().Int("max-resources", 0, "Stop the sync with an error once more resources than this were listed across all types, 0 means no limit. ($BATON_MAX_RESOURCES)")
+	cmd.PersistentFlags().StringToString("log-levels", nil, "Log levels of specific components (client, pagination, grants), e.g. client=debug. ($BATON_LOG_LEVELS)")
+	cmd.PersistentFlags().Bool("security-counts", false, "Add the number of securities of each issuer to its profile, at the cost of a request per issuer. ($BATON_SECURITY_COUNTS)")
+	cmd.PersistentFlags().String("display-name-template", "", "Go template formatting resource display names, e.g. '{{.Name}} ({{.Type}})', falling back to the legal name. ($BATON_DISPLAY_NAME_TEMPLATE)")
+	cmd.PersistentFlags().StringToString("resource-page-sizes", nil, "Page sizes of listings per resource type, e.g. issuer=100,investor=10. ($BATON_RESOURCE_PAGE_SIZES)")
 }
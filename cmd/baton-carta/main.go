@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/ConductorOne/baton-carta/pkg/carta"
 	"github.com/ConductorOne/baton-carta/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/cli"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/sdk"
 	"github.com/conductorone/baton-sdk/pkg/types"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
@@ -19,16 +22,12 @@ var version = "dev"
 func main() {
 	ctx := context.Background()
 
-	cfg := &config{}
-	cmd, err := cli.NewCmd(ctx, "baton-carta", cfg, validateConfig, getConnector, run)
+	cmd, err := newCmd(ctx)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 
-	cmd.Version = version
-	cmdFlags(cmd)
-
 	err = cmd.Execute()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -36,12 +35,26 @@ func main() {
 	}
 }
 
+// newCmd returns the baton-carta command with the connector flags and subcommands.
+func newCmd(ctx context.Context) (*cobra.Command, error) {
+	cfg := &config{}
+	cmd, err := cli.NewCmd(ctx, "baton-carta", cfg, validateConfig, getConnector, run)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Version = version
+	cmdFlags(cmd)
+	cmd.AddCommand(configSchemaCmd(cmd))
+
+	return cmd, nil
+}
+
 func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	cartaConnector, err := connector.New(ctx, cfg.AccessToken)
+	cartaConnector, err := newCartaConnector(ctx, cfg)
 	if err != nil {
-		l.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
 
@@ -54,11 +67,168 @@ func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, erro
 	return connector, nil
 }
 
+func newCartaConnector(ctx context.Context, cfg *config) (*connector.Carta, error) {
+	l := ctxzap.Extract(ctx)
+
+	var opts []connector.Option
+	if cfg.BaseURL != "" && cfg.BaseURL != carta.BaseURL {
+		opts = append(opts, connector.WithBaseURL(cfg.BaseURL))
+	}
+
+	if cfg.UpdatedSince != "" {
+		updatedSince, err := time.Parse(time.RFC3339, cfg.UpdatedSince)
+		if err != nil {
+			l.Error("error parsing updated-since", zap.Error(err))
+			return nil, err
+		}
+
+		opts = append(opts, connector.WithUpdatedSince(updatedSince))
+	}
+
+	if cfg.InsecureSkipVerify {
+		opts = append(opts, connector.WithInsecureSkipVerify(true))
+	}
+
+	if len(cfg.DebugHeaders) > 0 {
+		opts = append(opts, connector.WithDebugHeaders(cfg.DebugHeaders))
+	}
+
+	if len(cfg.ExtraQueryParams) > 0 {
+		opts = append(opts, connector.WithExtraQueryParams(cfg.ExtraQueryParams))
+	}
+
+	if cfg.ContinueOnPageError {
+		opts = append(opts, connector.WithContinueOnPageError(true))
+	}
+
+	if cfg.LazyIssuers {
+		opts = append(opts, connector.WithLazyIssuers(true))
+	}
+
+	if cfg.SplitFunds {
+		opts = append(opts, connector.WithSplitFunds(true))
+	}
+
+	opts = append(opts, connector.WithStakeholderWarningThreshold(cfg.StakeholderWarningThreshold))
+
+	if cfg.AcceptHeader != "" {
+		opts = append(opts, connector.WithAcceptHeader(cfg.AcceptHeader))
+	}
+
+	if cfg.AdaptivePageSize {
+		opts = append(opts, connector.WithAdaptivePageSize(true))
+	}
+
+	if len(cfg.StartPageTokens) > 0 {
+		opts = append(opts, connector.WithStartTokens(cfg.StartPageTokens))
+	}
+
+	if cfg.MaxConcurrentRequests > 0 {
+		opts = append(opts, connector.WithMaxConcurrentRequests(cfg.MaxConcurrentRequests))
+	}
+
+	if cfg.DetectPaginationDrift {
+		opts = append(opts, connector.WithPaginationDriftDetection(true))
+	}
+
+	if cfg.ResourceIdPrefix != "" {
+		opts = append(opts, connector.WithResourceIdPrefix(cfg.ResourceIdPrefix))
+	}
+
+	if cfg.MaxPageSize > 0 {
+		opts = append(opts, connector.WithMaxPageSize(cfg.MaxPageSize))
+	}
+
+	if cfg.SkipEmptyPortfolios {
+		opts = append(opts, connector.WithSkipEmptyPortfolios(true))
+	}
+
+	if cfg.PortfolioNamePrefix != "" {
+		opts = append(opts, connector.WithPortfolioNamePrefix(cfg.PortfolioNamePrefix))
+	}
+
+	if cfg.MaxPortfolioIssuerPages > 0 {
+		opts = append(opts, connector.WithMaxPortfolioIssuerPages(cfg.MaxPortfolioIssuerPages))
+	}
+
+	if !cfg.RetryOnTimeout {
+		opts = append(opts, connector.WithRetryOnTimeout(false))
+	}
+
+	opts = append(opts, connector.WithCircuitBreaker(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown))
+
+	if cfg.RequestTimeout > 0 {
+		opts = append(opts, connector.WithRequestTimeout(cfg.RequestTimeout))
+	}
+
+	if len(cfg.OperationTimeouts) > 0 {
+		operationTimeouts, err := parseOperationTimeouts(cfg.OperationTimeouts)
+		if err != nil {
+			l.Error("error parsing operation-timeouts", zap.Error(err))
+			return nil, err
+		}
+
+		opts = append(opts, connector.WithOperationTimeouts(operationTimeouts))
+	}
+
+	if cfg.MaxResources > 0 {
+		opts = append(opts, connector.WithMaxResources(cfg.MaxResources))
+	}
+
+	if len(cfg.LogLevels) > 0 {
+		logLevels, err := carta.ParseLogLevels(cfg.LogLevels)
+		if err != nil {
+			l.Error("error parsing log-levels", zap.Error(err))
+			return nil, err
+		}
+
+		opts = append(opts, connector.WithLogLevels(logLevels))
+	}
+
+	if cfg.SecurityCounts {
+		opts = append(opts, connector.WithSecurityCounts(true))
+	}
+
+	if cfg.DisplayNameTemplate != "" {
+		opts = append(opts, connector.WithDisplayNameTemplate(cfg.DisplayNameTemplate))
+	}
+
+	if len(cfg.ResourcePageSizes) > 0 {
+		pageSizes, err := parseResourcePageSizes(cfg.ResourcePageSizes)
+		if err != nil {
+			l.Error("error parsing resource-page-sizes", zap.Error(err))
+			return nil, err
+		}
+
+		opts = append(opts, connector.WithResourcePageSizes(pageSizes))
+	}
+
+	cartaConnector, err := connector.New(ctx, cfg.AccessToken, opts...)
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+
+	return cartaConnector, nil
+}
+
 // run is where the process of syncing with the connector is implemented.
 func run(ctx context.Context, cfg *config) error {
 	l := ctxzap.Extract(ctx)
 
-	c, err := getConnector(ctx, cfg)
+	cartaConnector, err := newCartaConnector(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	// flush and clear connector state at the end of the sync, even if it was cancelled
+	defer func() {
+		if err := cartaConnector.Close(context.Background()); err != nil {
+			l.Error("error closing connector", zap.Error(err))
+		}
+	}()
+
+	c, err := connectorbuilder.NewConnector(ctx, cartaConnector)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return err
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix is the prefix of the environment variables the configuration is read from.
+const envPrefix = "BATON_"
+
+// requiredFields are the configuration fields the connector can't run without.
+var requiredFields = map[string]bool{
+	"token": true,
+}
+
+// envSuffix matches the "($BATON_...)" suffix of flag usages.
+var envSuffix = regexp.MustCompile(`\s*\(\$[A-Z0-9_]+\)$`)
+
+// configField describes a single configuration field accepted by the connector.
+type configField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Env         string `json:"env"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// configSchemaCmd returns a command printing the configuration fields accepted by the connector as JSON,
+// so operators can check their configuration against it.
+func configSchemaCmd(root *cobra.Command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "config-schema",
+		Short: "Print the configuration fields accepted by the connector as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var fields []configField
+			root.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+				fields = append(fields, configField{
+					Name:        flag.Name,
+					Type:        flag.Value.Type(),
+					Default:     flag.DefValue,
+					Env:         envPrefix + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_")),
+					Required:    requiredFields[flag.Name],
+					Description: envSuffix.ReplaceAllString(flag.Usage, ""),
+				})
+			})
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(fields)
+		},
+	}
+}
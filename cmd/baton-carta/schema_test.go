@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	"github.com/spf13/pflag"
+)
+
+func TestConfigSchemaListsEveryFlag(t *testing.T) {
+	cmd, err := newCmd(context.Background())
+	if err != nil {
+		t.Fatalf("newCmd() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"config-schema"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config-schema error = %v", err)
+	}
+
+	var fields []configField
+	if err := json.Unmarshal(out.Bytes(), &fields); err != nil {
+		t.Fatalf("config-schema printed invalid JSON: %v\n%s", err, out.String())
+	}
+
+	byName := make(map[string]configField, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+
+	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		field, ok := byName[flag.Name]
+		if !ok {
+			t.Errorf("flag %s is missing from the schema", flag.Name)
+			return
+		}
+
+		if field.Default != flag.DefValue {
+			t.Errorf("field %s default = %q, want %q", flag.Name, field.Default, flag.DefValue)
+		}
+
+		if strings.Contains(field.Description, "$BATON_") {
+			t.Errorf("field %s description %q still carries the environment variable", flag.Name, field.Description)
+		}
+	})
+
+	if len(fields) != len(byName) {
+		t.Errorf("schema lists %d fields under %d names", len(fields), len(byName))
+	}
+
+	token := byName["token"]
+	if !token.Required || token.Env != "BATON_TOKEN" {
+		t.Errorf("token field = %+v, want the required BATON_TOKEN field", token)
+	}
+
+	baseURL := byName["base-url"]
+	if baseURL.Required || baseURL.Default != carta.BaseURL || baseURL.Env != "BATON_BASE_URL" {
+		t.Errorf("base-url field = %+v, want the optional BATON_BASE_URL field defaulting to %s", baseURL, carta.BaseURL)
+	}
+
+	if retry := byName["retry-on-timeout"]; retry.Type != "bool" || retry.Default != "true" {
+		t.Errorf("retry-on-timeout field = %+v, want a bool defaulting to true", retry)
+	}
+}
@@ -0,0 +1,75 @@
+package carta
+
+import "sync"
+
+// issuerCache keeps issuers fetched by id for the duration of a sync, as portfolio grants
+// look up the same issuers repeatedly.
+type issuerCache struct {
+	mtx     sync.RWMutex
+	issuers map[string]Issuer
+}
+
+func newIssuerCache() *issuerCache {
+	return &issuerCache{
+		issuers: make(map[string]Issuer),
+	}
+}
+
+func (ic *issuerCache) get(issuerId string) (Issuer, bool) {
+	ic.mtx.RLock()
+	defer ic.mtx.RUnlock()
+
+	issuer, ok := ic.issuers[issuerId]
+
+	return issuer, ok
+}
+
+func (ic *issuerCache) put(issuerId string, issuer Issuer) {
+	ic.mtx.Lock()
+	defer ic.mtx.Unlock()
+
+	ic.issuers[issuerId] = issuer
+}
+
+func (ic *issuerCache) clear() {
+	ic.mtx.Lock()
+	defer ic.mtx.Unlock()
+
+	ic.issuers = make(map[string]Issuer)
+}
+
+// issuerContactCache keeps the primary contacts fetched by issuer id for the duration of a sync, as both
+// the contact listing and the issuer grants need them. Issuers without contact are cached as nil.
+type issuerContactCache struct {
+	mtx      sync.RWMutex
+	contacts map[string]*IssuerContact
+}
+
+func newIssuerContactCache() *issuerContactCache {
+	return &issuerContactCache{
+		contacts: make(map[string]*IssuerContact),
+	}
+}
+
+func (cc *issuerContactCache) get(issuerId string) (*IssuerContact, bool) {
+	cc.mtx.RLock()
+	defer cc.mtx.RUnlock()
+
+	contact, ok := cc.contacts[issuerId]
+
+	return contact, ok
+}
+
+func (cc *issuerContactCache) put(issuerId string, contact *IssuerContact) {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	cc.contacts[issuerId] = contact
+}
+
+func (cc *issuerContactCache) clear() {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	cc.contacts = make(map[string]*IssuerContact)
+}
@@ -0,0 +1,94 @@
+package carta
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Circuit breaker defaults of the client.
+const (
+	DefaultCircuitBreakerThreshold = 5
+	DefaultCircuitBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned when requests are short-circuited after sustained Carta API failures.
+var ErrCircuitOpen = errors.New("carta: circuit open, too many consecutive request failures")
+
+type circuitBreaker struct {
+	mtx                 sync.Mutex
+	threshold           int
+	cooldown            time.Duration
+	consecutiveFailures int
+	openedAt            time.Time
+	halfOpen            bool
+	now                 func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a request may be attempted. Once the cooldown has elapsed
+// the breaker half-opens and lets a single trial request through, reporting it as the trial.
+// A trial must end with record or, when no outcome was observed, with abandon.
+func (cb *circuitBreaker) allow() (bool, error) {
+	if cb == nil || cb.threshold <= 0 {
+		return false, nil
+	}
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	if cb.openedAt.IsZero() {
+		return false, nil
+	}
+
+	if cb.halfOpen || cb.now().Sub(cb.openedAt) < cb.cooldown {
+		return false, ErrCircuitOpen
+	}
+
+	cb.halfOpen = true
+
+	return true, nil
+}
+
+// abandon ends a trial request that never reached Carta, e.g. because the sync was cancelled, so
+// the next request can be the trial instead of the breaker staying half-open for good.
+func (cb *circuitBreaker) abandon() {
+	if cb == nil || cb.threshold <= 0 {
+		return
+	}
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	cb.halfOpen = false
+}
+
+// record tracks the outcome of an attempted request.
+func (cb *circuitBreaker) record(failed bool) {
+	if cb == nil || cb.threshold <= 0 {
+		return
+	}
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	if !failed {
+		cb.consecutiveFailures = 0
+		cb.openedAt = time.Time{}
+		cb.halfOpen = false
+		return
+	}
+
+	cb.consecutiveFailures++
+	if cb.halfOpen || cb.consecutiveFailures >= cb.threshold {
+		cb.openedAt = cb.now()
+		cb.halfOpen = false
+	}
+}
@@ -0,0 +1,178 @@
+package carta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClock is a clock the test moves forward by hand.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*circuitBreaker, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cb := newCircuitBreaker(threshold, cooldown)
+	cb.now = clock.Now
+
+	return cb, clock
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	cb, _ := newTestBreaker(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cb.allow(); err != nil {
+			t.Fatalf("request %d was short-circuited before the threshold: %v", i, err)
+		}
+		cb.record(true)
+	}
+
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	cb, _ := newTestBreaker(2, time.Minute)
+
+	cb.record(true)
+	cb.record(false)
+	cb.record(true)
+
+	if _, err := cb.allow(); err != nil {
+		t.Fatalf("allow() error = %v, failures should not add up across a success", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpensAfterCooldown(t *testing.T) {
+	cb, clock := newTestBreaker(1, time.Minute)
+	cb.record(true)
+
+	clock.now = clock.now.Add(time.Minute)
+
+	trial, err := cb.allow()
+	if err != nil || !trial {
+		t.Fatalf("allow() = %v, %v, want the trial request after the cooldown", trial, err)
+	}
+
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() error = %v, want ErrCircuitOpen while the trial is in flight", err)
+	}
+
+	cb.record(false)
+
+	trial, err = cb.allow()
+	if err != nil || trial {
+		t.Fatalf("allow() = %v, %v, want the closed breaker to let requests through", trial, err)
+	}
+}
+
+func TestCircuitBreakerFailedTrialReopens(t *testing.T) {
+	cb, clock := newTestBreaker(3, time.Minute)
+	for i := 0; i < 3; i++ {
+		cb.record(true)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if trial, err := cb.allow(); err != nil || !trial {
+		t.Fatalf("allow() = %v, %v, want the trial request", trial, err)
+	}
+	cb.record(true)
+
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() error = %v, want a failed trial to restart the cooldown", err)
+	}
+}
+
+func TestCircuitBreakerAbandonedTrial(t *testing.T) {
+	cb, clock := newTestBreaker(1, time.Minute)
+	cb.record(true)
+
+	clock.now = clock.now.Add(time.Minute)
+	if trial, err := cb.allow(); err != nil || !trial {
+		t.Fatalf("allow() = %v, %v, want the trial request", trial, err)
+	}
+	cb.abandon()
+
+	if trial, err := cb.allow(); err != nil || !trial {
+		t.Fatalf("allow() = %v, %v, want another trial once the first was abandoned", trial, err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb, _ := newTestBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		cb.record(true)
+	}
+
+	if _, err := cb.allow(); err != nil {
+		t.Fatalf("allow() error = %v, a zero threshold disables the breaker", err)
+	}
+}
+
+// TestClientReleasesCancelledTrial covers a trial request that never reaches Carta because the sync
+// was cancelled while it waited for a request slot, which must not leave the breaker half-open.
+func TestClientReleasesCancelledTrial(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"issuer": {"id": "acme", "legalName": "Acme Corp"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		"token",
+		server.Client(),
+		WithBaseURL(server.URL),
+		WithRetry(0, time.Millisecond, time.Millisecond),
+		WithCircuitBreaker(1, time.Minute),
+		WithMaxConcurrentRequests(1),
+	)
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client.breaker.now = clock.Now
+
+	ctx := context.Background()
+	if _, err := client.GetIssuer(ctx, "acme"); err == nil {
+		t.Fatal("GetIssuer() succeeded, want the server error")
+	}
+
+	if _, err := client.GetIssuer(ctx, "acme"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("GetIssuer() error = %v, want ErrCircuitOpen", err)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+
+	// the trial waits for the only request slot until its context is cancelled
+	client.inFlight.slots <- struct{}{}
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.GetIssuer(cancelledCtx, "acme"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetIssuer() error = %v, want context.Canceled", err)
+	}
+	client.inFlight.release()
+
+	failing.Store(false)
+	issuer, err := client.GetIssuer(ctx, "acme")
+	if err != nil {
+		t.Fatalf("GetIssuer() error = %v, want the breaker to let a new trial through", err)
+	}
+
+	if issuer.Name != "Acme Corp" {
+		t.Errorf("GetIssuer() name = %q, want Acme Corp", issuer.Name)
+	}
+}
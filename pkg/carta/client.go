@@ -1,43 +1,130 @@
 package carta
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// BaseURL is the Carta API base URL requests are sent to unless WithBaseURL sets another one.
 const BaseURL = "https://mock-api.carta.com/v1alpha1/"
+const ChangesBaseURL = BaseURL + "changes"
 const InvestorsBaseURL = BaseURL + "investors/firms"
+const InvestorMembersBaseURL = InvestorsBaseURL + "/%s/members"
+const InvestorIssuersBaseURL = InvestorsBaseURL + "/%s/issuers"
+const InvestorContactsBaseURL = InvestorsBaseURL + "/%s/contacts"
 const IssuersBaseURL = BaseURL + "issuers"
 const IssuerBaseURL = IssuersBaseURL + "/%s"
+const IssuerContactBaseURL = IssuerBaseURL + "/contact"
+const IssuerDocumentsBaseURL = IssuerBaseURL + "/documents"
+const IssuerBoardMembersBaseURL = IssuerBaseURL + "/board-members"
+const IssuerSecuritiesBaseURL = IssuerBaseURL + "/securities"
+const LimitsBaseURL = BaseURL + "limits"
 const PortfoliosBaseURL = BaseURL + "portfolios"
 const PortfoliosIssuersBaseURL = PortfoliosBaseURL + "/%s/issuers"
+const PortfolioFirmsBaseURL = PortfoliosBaseURL + "/%s/firms"
+
+const defaultAcceptHeader = "application/json"
+
+// defaultTerminalPageTokens are next page tokens some APIs return instead of an empty string on the last page.
+var defaultTerminalPageTokens = []string{"null", "0"}
 
 type Client struct {
-	httpClient  *http.Client
-	accessToken string
+	httpClient         *http.Client
+	accessToken        string
+	baseURL            string
+	breaker            *circuitBreaker
+	terminalPageTokens []string
+	maxResponseSize    int64
+	debugHeaders       []string
+	extraQueryParams   map[string]string
+	retry              *retryPolicy
+	metrics            Metrics
+	tracer             Tracer
+	flights            *flightGroup
+	pageLimit          *pageSizeLimit
+	issuers            *issuerCache
+	contacts           *issuerContactCache
+	// noSecurities is set once the securities endpoint turned out to be unavailable.
+	noSecurities      atomic.Bool
+	acceptHeader      string
+	inFlight          *requestSemaphore
+	drift             *driftDetector
+	deprecation       *deprecationNotice
+	logLevels         LogLevels
+	maxIssuerPages    int
+	timeout           time.Duration
+	operationTimeouts map[string]time.Duration
+}
+
+// ClientOption configures optional behaviour of the Carta client.
+type ClientOption func(*Client)
+
+// WithCircuitBreaker opens the circuit after the given number of consecutive failures and
+// short-circuits requests for the cooldown window. A threshold of zero disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+type LimitsResponse struct {
+	MaxPageSize int `json:"maxPageSize"`
 }
 
 type IssuerResponse struct {
 	Issuer Issuer `json:"issuer"`
 }
 
+type IssuerContactResponse struct {
+	Contact IssuerContact `json:"contact"`
+}
+
 type IssuersResponse struct {
 	Issuers []Issuer `json:"issuers"`
 	PaginationData
 }
 
+type IssuerDocumentsResponse struct {
+	Documents []IssuerDocument `json:"documents"`
+	PaginationData
+}
+
+type BoardMembersResponse struct {
+	Members []BoardMember `json:"boardMembers"`
+	PaginationData
+}
+
+// SecuritiesResponse only carries the pagination data, securities are counted rather than listed.
+type SecuritiesResponse struct {
+	PaginationData
+}
+
 type PortfoliosResponse struct {
 	Portfolios []Portfolio `json:"portfolios"`
 	PaginationData
 }
 
+type PortfolioFirmsResponse struct {
+	Firms []InvestorFirm `json:"firms"`
+	PaginationData
+}
+
 type PortfoliosIssuersResponse struct {
 	Issuers []Issuer `json:"issuers"`
 	PaginationData
@@ -48,18 +135,208 @@ type InvestorsResponse struct {
 	PaginationData
 }
 
+type InvestorContactsResponse struct {
+	Contacts []InvestorContact `json:"contacts"`
+	PaginationData
+}
+
+type InvestorMembersResponse struct {
+	Members []InvestorMember `json:"members"`
+	PaginationData
+}
+
+type ChangesResponse struct {
+	Changes []Change `json:"changes"`
+	PaginationData
+}
+
 type PaginationParams struct {
 	Size  int    `json:"pageSize"`
 	After string `json:"pageToken"`
+	// UpdatedSince limits results to resources changed after the given time (zero value means full sync).
+	UpdatedSince time.Time `json:"updatedSince"`
+	// NamePrefix limits results to resources whose name starts with the prefix, only supported by portfolios.
+	NamePrefix string `json:"namePrefix"`
+}
+
+// WithBaseURL sends requests to another Carta API base URL than BaseURL, e.g. a staging or mock API.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	}
+}
+
+// WithTerminalPageTokens sets the next page tokens that are treated as the end of pages.
+func WithTerminalPageTokens(tokens ...string) ClientOption {
+	return func(c *Client) {
+		c.terminalPageTokens = tokens
+	}
 }
 
-func NewClient(accessToken string, httpClient *http.Client) *Client {
-	return &Client{
-		accessToken: accessToken,
-		httpClient:  httpClient,
+// WithMaxResponseSize caps the number of bytes read from a single response body, zero disables the cap.
+func WithMaxResponseSize(maxBytes int64) ClientOption {
+	return func(c *Client) {
+		c.maxResponseSize = maxBytes
 	}
 }
 
+// WithDebugHeaders logs the allow-listed request headers of every outgoing request at debug level.
+// The authorization header is never logged, even if allow-listed.
+func WithDebugHeaders(headers ...string) ClientOption {
+	return func(c *Client) {
+		c.debugHeaders = nil
+		for _, header := range headers {
+			if strings.EqualFold(header, "authorization") {
+				continue
+			}
+
+			c.debugHeaders = append(c.debugHeaders, header)
+		}
+	}
+}
+
+// WithExtraQueryParams adds the given query parameters to every request, without overriding
+// parameters set by the request itself (e.g. pagination).
+func WithExtraQueryParams(params map[string]string) ClientOption {
+	return func(c *Client) {
+		c.extraQueryParams = params
+	}
+}
+
+// WithRetry retries failed requests up to maxRetries times with exponential backoff
+// between baseDelay and maxDelay. Zero retries disables retrying.
+func WithRetry(maxRetries int, baseDelay time.Duration, maxDelay time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retry.maxRetries = maxRetries
+		c.retry.baseDelay = baseDelay
+		c.retry.maxDelay = maxDelay
+	}
+}
+
+// WithJitterStrategy sets how retry backoff delays are randomized, defaults to FullJitter.
+func WithJitterStrategy(jitter JitterStrategy) ClientOption {
+	return func(c *Client) {
+		c.retry.jitter = jitter
+	}
+}
+
+// WithRetryOnTimeout controls whether attempts that timed out are retried, defaults to retrying
+// as the client only sends idempotent reads.
+func WithRetryOnTimeout(retryTimeouts bool) ClientOption {
+	return func(c *Client) {
+		c.retry.retryTimeouts = retryTimeouts
+	}
+}
+
+// WithMetrics reports request count, latency, retries and errors per operation to the given sink.
+func WithMetrics(metrics Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = metrics
+	}
+}
+
+// WithTracer wraps every request in a span of the given tracer.
+func WithTracer(tracer Tracer) ClientOption {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+// WithAcceptHeader overrides the accept header sent with every request, e.g. for vendor versioned responses.
+func WithAcceptHeader(accept string) ClientOption {
+	return func(c *Client) {
+		c.acceptHeader = accept
+	}
+}
+
+// WithMaxConcurrentRequests caps the number of requests in flight to Carta at the same time,
+// independently of rate limiting. Zero leaves concurrency unlimited.
+func WithMaxConcurrentRequests(maxInFlight int) ClientOption {
+	return func(c *Client) {
+		c.inFlight = newRequestSemaphore(maxInFlight)
+	}
+}
+
+// WithPaginationDriftDetection warns when the total count reported by a listing changes between its pages.
+func WithPaginationDriftDetection(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.drift = nil
+		if enabled {
+			c.drift = newDriftDetector()
+		}
+	}
+}
+
+// WithRequestTimeout bounds every request attempt to the given duration, zero disables the timeout.
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithOperationTimeouts overrides the request timeout of specific operations, keyed by client method
+// name (e.g. GetIssuersForPortfolio). Operations without an override use the request timeout.
+func WithOperationTimeouts(timeouts map[string]time.Duration) ClientOption {
+	return func(c *Client) {
+		c.operationTimeouts = timeouts
+	}
+}
+
+// WithLogLevels sets the log level of specific log components, e.g. LogComponentClient.
+func WithLogLevels(levels LogLevels) ClientOption {
+	return func(c *Client) {
+		c.logLevels = levels
+	}
+}
+
+// WithMaxPortfolioIssuerPages caps the number of issuer pages fetched per portfolio, issuers past
+// the cap are left out and the portfolio is marked as truncated. Zero fetches all pages.
+func WithMaxPortfolioIssuerPages(maxPages int) ClientOption {
+	return func(c *Client) {
+		c.maxIssuerPages = maxPages
+	}
+}
+
+// WithMaxPageSize sets the page size cap used when the API limits can't be discovered, zero means no cap.
+func WithMaxPageSize(maxPageSize int) ClientOption {
+	return func(c *Client) {
+		c.pageLimit.fallback = maxPageSize
+	}
+}
+
+func NewClient(accessToken string, httpClient *http.Client, opts ...ClientOption) *Client {
+	client := &Client{
+		accessToken:        accessToken,
+		httpClient:         httpClient,
+		baseURL:            BaseURL,
+		breaker:            newCircuitBreaker(DefaultCircuitBreakerThreshold, DefaultCircuitBreakerCooldown),
+		terminalPageTokens: defaultTerminalPageTokens,
+		maxResponseSize:    defaultMaxResponseSize,
+		retry:              newRetryPolicy(),
+		metrics:            noopMetrics{},
+		tracer:             noopTracer{},
+		flights:            newFlightGroup(),
+		pageLimit:          &pageSizeLimit{},
+		issuers:            newIssuerCache(),
+		contacts:           newIssuerContactCache(),
+		acceptHeader:       defaultAcceptHeader,
+		deprecation:        &deprecationNotice{},
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	// redirects use the client's own policy, without changing the HTTP client passed in
+	if httpClient != nil {
+		redirecting := *httpClient
+		redirecting.CheckRedirect = client.checkRedirect
+		client.httpClient = &redirecting
+	}
+
+	return client
+}
+
 func setupPaginationQuery(query url.Values, size int, after string) url.Values {
 	// add size
 	if size != 0 {
@@ -74,37 +351,94 @@ func setupPaginationQuery(query url.Values, size int, after string) url.Values {
 	return query
 }
 
+func setupUpdatedSinceQuery(query url.Values, updatedSince time.Time) url.Values {
+	// add last sync timestamp (omitted on full syncs)
+	if !updatedSince.IsZero() {
+		query.Add("updatedSince", updatedSince.UTC().Format(time.RFC3339))
+	}
+
+	return query
+}
+
+// resourceURL fills the id into a single resource URL template, escaping it as a path segment.
+func resourceURL(template string, id string) string {
+	return fmt.Sprintf(template, url.PathEscape(id))
+}
+
+// endpointURL moves an endpoint of the default base URL to the configured base URL.
+func (c *Client) endpointURL(endpoint string) string {
+	if c.baseURL == BaseURL {
+		return endpoint
+	}
+
+	return c.baseURL + strings.TrimPrefix(endpoint, BaseURL)
+}
+
+// NormalizeId returns the canonical form of a Carta id, so ids returned by different endpoints compare equal.
+// It's only meant for comparing ids and keying lookups, requests and resource ids use the ids as Carta returns them.
+func NormalizeId(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// ClearCache drops the data cached during a sync, so the next sync fetches it again.
+func (c *Client) ClearCache() {
+	c.issuers.clear()
+	c.contacts.clear()
+}
+
+// Close flushes buffered metrics and clears cached data, it is safe to call multiple times.
+func (c *Client) Close(ctx context.Context) error {
+	c.ClearCache()
+
+	if flusher, ok := c.metrics.(MetricsFlusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+// nextPageToken returns the token for the next page, or empty string when there are no more pages.
+func (c *Client) nextPageToken(after string, next string) string {
+	// check for duplicates to prevent infinite loop (this can happen with mock data)
+	if next == "" || next == after {
+		return ""
+	}
+
+	for _, terminal := range c.terminalPageTokens {
+		if next == terminal {
+			return ""
+		}
+	}
+
+	return next
+}
+
 // GetIssuers returns all issuers (companies to invest in) accessible to the user or investor.
 func (c *Client) GetIssuers(ctx context.Context, getIssuerVars PaginationParams) ([]Issuer, string, error) {
 	queryParams := setupPaginationQuery(url.Values{}, getIssuerVars.Size, getIssuerVars.After)
-	var issuersResponse IssuersResponse
-
-	err := c.doRequest(
-		ctx,
-		IssuersBaseURL,
-		&issuersResponse,
-		queryParams,
-	)
+	queryParams = setupUpdatedSinceQuery(queryParams, getIssuerVars.UpdatedSince)
 
+	issuersResponse, next, err := getPage[IssuersResponse](ctx, c, "GetIssuers", IssuersBaseURL, getIssuerVars.After, queryParams)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// check for duplicates to prevent infinite loop (this can happen with mock data)
-	if getIssuerVars.After != issuersResponse.Next && issuersResponse.Next != "" {
-		return issuersResponse.Issuers, issuersResponse.Next, nil
-	}
-
-	return issuersResponse.Issuers, "", nil
+	return issuersResponse.Issuers, next, nil
 }
 
 // GetIssuer returns specific issuer based on provided id, accessible to the user or investor.
 func (c *Client) GetIssuer(ctx context.Context, issuerId string) (Issuer, error) {
+	cacheKey := NormalizeId(issuerId)
+	if issuer, ok := c.issuers.get(cacheKey); ok {
+		return issuer, nil
+	}
+
 	var issuerResponse IssuerResponse
 
 	err := c.doRequest(
 		ctx,
-		fmt.Sprintf(IssuerBaseURL, issuerId),
+		"GetIssuer",
+		resourceURL(IssuerBaseURL, issuerId),
 		&issuerResponse,
 		nil,
 	)
@@ -113,137 +447,810 @@ func (c *Client) GetIssuer(ctx context.Context, issuerId string) (Issuer, error)
 		return Issuer{}, err
 	}
 
+	c.issuers.put(cacheKey, issuerResponse.Issuer)
+
 	return issuerResponse.Issuer, nil
 }
 
-// GetPortfolios returns all portfolios (groupings of issuers) accessible to the user or investor.
-func (c *Client) GetPortfolios(ctx context.Context, getPortfolioVars PaginationParams) ([]Portfolio, string, error) {
-	queryParams := setupPaginationQuery(url.Values{}, getPortfolioVars.Size, getPortfolioVars.After)
-	var portfoliosResponse PortfoliosResponse
+// warmIssuerCacheConcurrency bounds the issuers fetched at the same time while warming the cache,
+// on top of the configured limit of requests in flight.
+const warmIssuerCacheConcurrency = 8
+
+// WarmIssuerCache fetches the issuers not cached yet concurrently, so the GetIssuer calls that follow,
+// e.g. while granting portfolio memberships, are served from the cache. Issuers that fail to load are
+// left out, GetIssuer reports their errors when they are asked for.
+func (c *Client) WarmIssuerCache(ctx context.Context, issuerIds []string) error {
+	// pending maps the cache key of each issuer to fetch to the id it's fetched by
+	pending := make(map[string]string, len(issuerIds))
+	for _, issuerId := range issuerIds {
+		cacheKey := NormalizeId(issuerId)
+		if cacheKey == "" {
+			continue
+		}
+
+		if _, ok := c.issuers.get(cacheKey); !ok {
+			if _, ok := pending[cacheKey]; !ok {
+				pending[cacheKey] = issuerId
+			}
+		}
+	}
+
+	slots := make(chan struct{}, warmIssuerCacheConcurrency)
+	var wg sync.WaitGroup
+	for _, issuerId := range pending {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(issuerId string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			_, _ = c.GetIssuer(ctx, issuerId)
+		}(issuerId)
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// GetIssuerContact returns the primary admin contact of specific issuer, or nil if the issuer has none.
+// Contacts are cached for the duration of a sync.
+func (c *Client) GetIssuerContact(ctx context.Context, issuerId string) (*IssuerContact, error) {
+	cacheKey := NormalizeId(issuerId)
+	if contact, ok := c.contacts.get(cacheKey); ok {
+		return contact, nil
+	}
+
+	var contactResponse IssuerContactResponse
 
 	err := c.doRequest(
 		ctx,
-		PortfoliosBaseURL,
-		&portfoliosResponse,
+		"GetIssuerContact",
+		resourceURL(IssuerContactBaseURL, issuerId),
+		&contactResponse,
+		nil,
+	)
+
+	if err != nil {
+		if status.Code(err) == codes.Code(http.StatusNotFound) {
+			c.contacts.put(cacheKey, nil)
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if contactResponse.Contact.Id == "" {
+		c.contacts.put(cacheKey, nil)
+		return nil, nil
+	}
+
+	c.contacts.put(cacheKey, &contactResponse.Contact)
+
+	return &contactResponse.Contact, nil
+}
+
+// GetDocumentsForIssuer returns the documents (board consents, filings) of specific issuer accessible to the user.
+func (c *Client) GetDocumentsForIssuer(ctx context.Context, issuerId string, getDocumentVars PaginationParams) ([]IssuerDocument, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getDocumentVars.Size, getDocumentVars.After)
+
+	documentsResponse, next, err := getPage[IssuerDocumentsResponse](
+		ctx,
+		c,
+		"GetDocumentsForIssuer",
+		resourceURL(IssuerDocumentsBaseURL, issuerId),
+		getDocumentVars.After,
 		queryParams,
 	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return documentsResponse.Documents, next, nil
+}
 
+// GetBoardMembersForIssuer returns the board members of specific issuer, issuers without a board have none.
+func (c *Client) GetBoardMembersForIssuer(ctx context.Context, issuerId string, getBoardMemberVars PaginationParams) ([]BoardMember, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getBoardMemberVars.Size, getBoardMemberVars.After)
+
+	boardMembersResponse, next, err := getPage[BoardMembersResponse](
+		ctx,
+		c,
+		"GetBoardMembersForIssuer",
+		resourceURL(IssuerBoardMembersBaseURL, issuerId),
+		getBoardMemberVars.After,
+		queryParams,
+	)
 	if err != nil {
+		if IsNotFound(err) {
+			return nil, "", nil
+		}
+
 		return nil, "", err
 	}
 
-	// get all issuers for each portfolio
-	for i, portfolio := range portfoliosResponse.Portfolios {
-		var issuers []Issuer
-		var next string
+	return boardMembersResponse.Members, next, nil
+}
 
-		// get issuers for portfolio ( loop until all issuers are retrieved )
-		for {
-			issuersForPortfolio, nextToken, err := c.GetIssuersForPortfolio(
-				ctx,
-				portfolio.Id,
-				PaginationParams{Size: 100, After: next},
+// GetSecurityCount returns the number of securities of specific issuer from the total count of a single
+// item page, without listing them. It reports false when the securities endpoint is unavailable.
+func (c *Client) GetSecurityCount(ctx context.Context, issuerId string) (int, bool, error) {
+	if c.noSecurities.Load() {
+		return 0, false, nil
+	}
+
+	var securitiesResponse SecuritiesResponse
+
+	err := c.doRequest(
+		ctx,
+		"GetSecurityCount",
+		resourceURL(IssuerSecuritiesBaseURL, issuerId),
+		&securitiesResponse,
+		setupPaginationQuery(url.Values{}, 1, ""),
+	)
+
+	if err != nil {
+		// the endpoint isn't available to every tenant, don't ask again for the other issuers
+		if status.Code(err) == codes.Code(http.StatusNotFound) || IsAccessDenied(err) {
+			c.noSecurities.Store(true)
+			return 0, false, nil
+		}
+
+		return 0, false, err
+	}
+
+	return securitiesResponse.Total, true, nil
+}
+
+// GetPortfolios returns all portfolios (groupings of issuers) accessible to the user or investor. Their issuers
+// and firms aren't fetched, see WalkIssuersForPortfolio and GetAllInvestorsForPortfolio.
+func (c *Client) GetPortfolios(ctx context.Context, getPortfolioVars PaginationParams) ([]Portfolio, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getPortfolioVars.Size, getPortfolioVars.After)
+	if getPortfolioVars.NamePrefix != "" {
+		queryParams.Add("namePrefix", getPortfolioVars.NamePrefix)
+	}
+
+	portfoliosResponse, next, err := getPage[PortfoliosResponse](ctx, c, "GetPortfolios", PortfoliosBaseURL, getPortfolioVars.After, queryParams)
+	if err != nil {
+		return nil, "", err
+	}
+
+	portfolios := make([]Portfolio, 0, len(portfoliosResponse.Portfolios))
+	for _, portfolio := range portfoliosResponse.Portfolios {
+		// a portfolio without id can't be synced nor have its issuers fetched
+		if strings.TrimSpace(portfolio.Id) == "" {
+			ctxzap.Extract(ctx).Warn(
+				"carta: skipping portfolio without id",
+				zap.String("portfolio_name", portfolio.Name),
 			)
+			continue
+		}
 
-			if err != nil {
-				return nil, "", err
-			}
+		portfolios = append(portfolios, portfolio)
+	}
 
-			issuers = append(issuers, issuersForPortfolio...)
+	return portfolios, next, nil
+}
 
-			if nextToken == "" {
-				break
+// GetInvestorsForPortfolio returns the investor firms that can access specific portfolio.
+func (c *Client) GetInvestorsForPortfolio(ctx context.Context, portfolioId string, getInvestorVars PaginationParams) ([]InvestorFirm, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getInvestorVars.Size, getInvestorVars.After)
+
+	firmsResponse, next, err := getPage[PortfolioFirmsResponse](
+		ctx,
+		c,
+		"GetInvestorsForPortfolio",
+		resourceURL(PortfolioFirmsBaseURL, portfolioId),
+		getInvestorVars.After,
+		queryParams,
+	)
+	if err != nil {
+		// portfolios not shared with any firm may have no firm listing, and tokens without investor
+		// visibility can't list the firms, either way the portfolio is synced without firms
+		if IsNotFound(err) || IsAccessDenied(err) {
+			return nil, "", nil
+		}
+
+		return nil, "", err
+	}
+
+	return firmsResponse.Firms, next, nil
+}
+
+// GetAllInvestorsForPortfolio walks the pages of investor firms that can access specific portfolio.
+func (c *Client) GetAllInvestorsForPortfolio(ctx context.Context, portfolioId string) ([]InvestorFirm, error) {
+	var firms []InvestorFirm
+	var next string
+
+	for {
+		page, nextToken, err := c.GetInvestorsForPortfolio(ctx, portfolioId, PaginationParams{Size: 100, After: next})
+		if err != nil {
+			return nil, err
+		}
+
+		firms = append(firms, page...)
+
+		if nextToken == "" {
+			return firms, nil
+		}
+
+		next = nextToken
+	}
+}
+
+// GetAllIssuersForPortfolio walks the pages of issuers under specific portfolio, up to the configured
+// page cap, e.g. for a targeted resync of a single portfolio's members.
+func (c *Client) GetAllIssuersForPortfolio(ctx context.Context, portfolioId string) ([]Issuer, error) {
+	issuers, _, err := c.walkIssuersForPortfolio(ctx, portfolioId)
+
+	return issuers, err
+}
+
+// PortfolioMembershipReport describes how the members of a portfolio resolve to issuers.
+type PortfolioMembershipReport struct {
+	PortfolioId string
+	// Members are the distinct member ids listed under the portfolio.
+	Members []string
+	// Unresolved maps the member ids that couldn't be fetched as issuers to the reason.
+	Unresolved map[string]error
+	// Truncated is set when the member listing hit the portfolio issuer page cap.
+	Truncated bool
+}
+
+// ValidatePortfolioMembership fetches the members of specific portfolio and each member issuer,
+// reporting the members that fail to resolve. It's meant as a diagnostic before relying on portfolio grants.
+func (c *Client) ValidatePortfolioMembership(ctx context.Context, portfolioId string) (PortfolioMembershipReport, error) {
+	report := PortfolioMembershipReport{
+		PortfolioId: portfolioId,
+		Unresolved:  make(map[string]error),
+	}
+
+	members, truncated, err := c.walkIssuersForPortfolio(ctx, report.PortfolioId)
+	if err != nil {
+		return PortfolioMembershipReport{}, err
+	}
+
+	report.Truncated = truncated
+
+	seen := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		memberId := member.Id
+		if _, ok := seen[NormalizeId(memberId)]; ok {
+			continue
+		}
+		seen[NormalizeId(memberId)] = struct{}{}
+		report.Members = append(report.Members, memberId)
+
+		if NormalizeId(memberId) == "" {
+			report.Unresolved[memberId] = errors.New("carta: portfolio member has no id")
+			continue
+		}
+
+		if _, err := c.GetIssuer(ctx, memberId); err != nil {
+			if ctx.Err() != nil {
+				return PortfolioMembershipReport{}, ctx.Err()
 			}
 
-			next = nextToken
+			report.Unresolved[memberId] = err
 		}
+	}
+
+	return report, nil
+}
 
-		portfoliosResponse.Portfolios[i].Issuers = issuers
+// walkIssuersForPortfolio fetches the issuers of a portfolio up to the configured page cap,
+// reporting whether issuers were left out.
+func (c *Client) walkIssuersForPortfolio(ctx context.Context, portfolioId string) ([]Issuer, bool, error) {
+	var issuers []Issuer
+
+	truncated, err := c.WalkIssuersForPortfolio(ctx, portfolioId, func(page []Issuer) error {
+		issuers = append(issuers, page...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
 	}
 
-	// check for duplicates to prevent infinite loop (this can happen with mock data)
-	if getPortfolioVars.After != portfoliosResponse.Next && portfoliosResponse.Next != "" {
-		return portfoliosResponse.Portfolios, portfoliosResponse.Next, nil
+	return issuers, truncated, nil
+}
+
+// WalkIssuersForPortfolio calls fn with each page of issuers under specific portfolio, up to the configured
+// page cap, without holding on to earlier pages. It reports whether issuers were left out, an error returned
+// by fn stops the walk.
+func (c *Client) WalkIssuersForPortfolio(ctx context.Context, portfolioId string, fn func([]Issuer) error) (bool, error) {
+	var next string
+
+	// get issuers for portfolio ( loop until all issuers are retrieved )
+	for pages := 1; ; pages++ {
+		issuersForPortfolio, nextToken, truncated, err := c.GetIssuersPageForPortfolio(ctx, portfolioId, next, pages)
+		if err != nil {
+			return false, err
+		}
+
+		if err := fn(issuersForPortfolio); err != nil {
+			return false, err
+		}
+
+		if truncated || nextToken == "" {
+			return truncated, nil
+		}
+
+		next = nextToken
+	}
+}
+
+// GetIssuersPageForPortfolio returns a page of the issuers under specific portfolio, for walks spread over
+// several calls. page is the number of the page within the walk, starting at 1. Once the configured page cap
+// is reached no next page token is returned, and the page reports whether issuers were left out.
+func (c *Client) GetIssuersPageForPortfolio(ctx context.Context, portfolioId string, after string, page int) ([]Issuer, string, bool, error) {
+	issuers, nextToken, err := c.GetIssuersForPortfolio(ctx, portfolioId, PaginationParams{Size: 100, After: after})
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if nextToken == "" || c.maxIssuerPages <= 0 || page < c.maxIssuerPages {
+		return issuers, nextToken, false, nil
 	}
 
-	return portfoliosResponse.Portfolios, "", nil
+	c.logLevels.Logger(ctx, LogComponentPagination).Warn(
+		"carta: portfolio issuer page cap reached, remaining issuers are not synced",
+		zap.String("portfolio_id", portfolioId),
+		zap.Int("max_pages", c.maxIssuerPages),
+	)
+
+	return issuers, "", true, nil
 }
 
 // GetIssuersForPortfolio returns all issuers (companies to invest in) under specific portfolio.
 func (c *Client) GetIssuersForPortfolio(ctx context.Context, portfolioId string, getIssuerVars PaginationParams) ([]Issuer, string, error) {
 	queryParams := setupPaginationQuery(url.Values{}, getIssuerVars.Size, getIssuerVars.After)
-	var issuersReponse PortfoliosIssuersResponse
 
-	err := c.doRequest(
+	issuersResponse, next, err := getPage[PortfoliosIssuersResponse](
 		ctx,
-		fmt.Sprintf(PortfoliosIssuersBaseURL, portfolioId),
-		&issuersReponse,
+		c,
+		"GetIssuersForPortfolio",
+		resourceURL(PortfoliosIssuersBaseURL, portfolioId),
+		getIssuerVars.After,
 		queryParams,
 	)
-
 	if err != nil {
 		return nil, "", err
 	}
 
-	// check for duplicates to prevent infinite loop (this can happen with mock data)
-	if getIssuerVars.After != issuersReponse.Next && issuersReponse.Next != "" {
-		return issuersReponse.Issuers, issuersReponse.Next, nil
-	}
-
-	return issuersReponse.Issuers, "", nil
+	return issuersResponse.Issuers, next, nil
 }
 
 // GetInvestors returns all investor firms accessible to the user.
 func (c *Client) GetInvestors(ctx context.Context, getInvestorVars PaginationParams) ([]InvestorFirm, string, error) {
 	queryParams := setupPaginationQuery(url.Values{}, getInvestorVars.Size, getInvestorVars.After)
-	var investorsResponse InvestorsResponse
+	queryParams = setupUpdatedSinceQuery(queryParams, getInvestorVars.UpdatedSince)
 
-	err := c.doRequest(
+	investorsResponse, next, err := getPage[InvestorsResponse](ctx, c, "GetInvestors", InvestorsBaseURL, getInvestorVars.After, queryParams)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return investorsResponse.Firms, next, nil
+}
+
+// GetIssuersForInvestor returns all issuers (companies invested in) of specific investor firm.
+func (c *Client) GetIssuersForInvestor(ctx context.Context, firmId string, getIssuerVars PaginationParams) ([]Issuer, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getIssuerVars.Size, getIssuerVars.After)
+
+	issuersResponse, next, err := getPage[IssuersResponse](
 		ctx,
-		InvestorsBaseURL,
-		&investorsResponse,
+		c,
+		"GetIssuersForInvestor",
+		resourceURL(InvestorIssuersBaseURL, firmId),
+		getIssuerVars.After,
 		queryParams,
 	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return issuersResponse.Issuers, next, nil
+}
 
+// GetInvestorMembers returns the users of a specific investor firm along with their roles.
+func (c *Client) GetInvestorMembers(ctx context.Context, firmId string, getMemberVars PaginationParams) ([]InvestorMember, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getMemberVars.Size, getMemberVars.After)
+
+	membersResponse, next, err := getPage[InvestorMembersResponse](
+		ctx,
+		c,
+		"GetInvestorMembers",
+		resourceURL(InvestorMembersBaseURL, firmId),
+		getMemberVars.After,
+		queryParams,
+	)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// check for duplicates to prevent infinite loop (this can happen with mock data)
-	if getInvestorVars.After != investorsResponse.Next && investorsResponse.Next != "" {
-		return investorsResponse.Firms, investorsResponse.Next, nil
+	return membersResponse.Members, next, nil
+}
+
+// GetInvestorContacts returns the named contacts of a specific investor firm along with the portfolios they can access.
+func (c *Client) GetInvestorContacts(ctx context.Context, firmId string, getContactVars PaginationParams) ([]InvestorContact, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getContactVars.Size, getContactVars.After)
+
+	contactsResponse, next, err := getPage[InvestorContactsResponse](
+		ctx,
+		c,
+		"GetInvestorContacts",
+		resourceURL(InvestorContactsBaseURL, firmId),
+		getContactVars.After,
+		queryParams,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return contactsResponse.Contacts, next, nil
+}
+
+// mergeExtraQueryParams adds configured extra query parameters that aren't already set on the request.
+func (c *Client) mergeExtraQueryParams(queryParams url.Values) url.Values {
+	if len(c.extraQueryParams) == 0 {
+		return queryParams
+	}
+
+	if queryParams == nil {
+		queryParams = url.Values{}
+	}
+
+	for key, value := range c.extraQueryParams {
+		if queryParams.Has(key) {
+			continue
+		}
+
+		queryParams.Set(key, value)
+	}
+
+	return queryParams
+}
+
+// encodeQuery builds the query string of a request. Request specific parameters take precedence over the
+// extra query parameters, which take precedence over a query already part of the endpoint. Every parameter
+// goes through url.Values.Encode, so no value reaches the URL unescaped.
+func (c *Client) encodeQuery(endpointQuery url.Values, queryParams url.Values) string {
+	queryParams = c.mergeExtraQueryParams(queryParams)
+	if queryParams == nil {
+		queryParams = url.Values{}
+	}
+
+	for key, values := range endpointQuery {
+		if queryParams.Has(key) {
+			continue
+		}
+
+		queryParams[key] = values
+	}
+
+	return queryParams.Encode()
+}
+
+// dumpHeaders logs the allow-listed headers of the request, never including the authorization header.
+func (c *Client) dumpHeaders(ctx context.Context, req *http.Request) {
+	if len(c.debugHeaders) == 0 {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+	}
+
+	for _, header := range c.debugHeaders {
+		if strings.EqualFold(header, "authorization") {
+			continue
+		}
+
+		if value := req.Header.Get(header); value != "" {
+			fields = append(fields, zap.String(http.CanonicalHeaderKey(header), value))
+		}
+	}
+
+	c.logLevels.Logger(ctx, LogComponentClient).Debug("carta: outgoing request headers", fields...)
+}
+
+// GetChanges returns references to resources created, updated or deleted since the given time.
+func (c *Client) GetChanges(ctx context.Context, since time.Time, getChangesVars PaginationParams) ([]Change, string, error) {
+	queryParams := setupPaginationQuery(url.Values{}, getChangesVars.Size, getChangesVars.After)
+	if !since.IsZero() {
+		queryParams.Add("since", since.UTC().Format(time.RFC3339))
+	}
+
+	changesResponse, next, err := getPage[ChangesResponse](ctx, c, "GetChanges", ChangesBaseURL, getChangesVars.After, queryParams)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return changesResponse.Changes, next, nil
+}
+
+// Counts returns the total number of issuers, portfolios and investor firms accessible to the user,
+// as reported by the total count of a single item page of each.
+func (c *Client) Counts(ctx context.Context) (Counts, error) {
+	var counts Counts
+
+	for _, resource := range []struct {
+		baseURL string
+		total   *int
+	}{
+		{IssuersBaseURL, &counts.Issuers},
+		{PortfoliosBaseURL, &counts.Portfolios},
+		{InvestorsBaseURL, &counts.Investors},
+	} {
+		var paginationData PaginationData
+
+		err := c.doRequest(
+			ctx,
+			"Counts",
+			resource.baseURL,
+			&paginationData,
+			setupPaginationQuery(url.Values{}, 1, ""),
+		)
+
+		if err != nil {
+			return Counts{}, err
+		}
+
+		*resource.total = paginationData.Total
 	}
 
-	return investorsResponse.Firms, "", nil
+	return counts, nil
 }
 
-func (c *Client) doRequest(ctx context.Context, url string, resourceResponse interface{}, queryParams url.Values) error {
+// pagedResponse is satisfied by every list response through its embedded PaginationData.
+type pagedResponse interface {
+	pagination() PaginationData
+}
+
+// getPage requests a single page of a list endpoint, decodes it into the typed response
+// and returns it along with the token of the next page, if any.
+func getPage[T pagedResponse](
+	ctx context.Context,
+	c *Client,
+	operation string,
+	endpoint string,
+	after string,
+	queryParams url.Values,
+) (T, string, error) {
+	var response T
+
+	c.clampPageSize(ctx, queryParams)
+
+	err := c.doRequest(ctx, operation, endpoint, &response, queryParams)
+	if err != nil {
+		return response, "", err
+	}
+
+	c.drift.observe(c.logLevels.Logger(ctx, LogComponentPagination), endpoint, after, response.pagination().Total)
+
+	return response, c.nextPageToken(after, response.pagination().Next), nil
+}
+
+func (c *Client) doRequest(ctx context.Context, operation string, url string, resourceResponse interface{}, queryParams url.Values) error {
+	url = c.endpointURL(url)
+
+	ctx, span := c.tracer.Start(ctx, operation)
+	start := time.Now()
+	statusCode := 0
+	attempts := 0
+	shared := false
+	// err is the error the request ends with, every return below goes through it so the span records it
+	var err error
+	defer func() {
+		span.SetAttributes(map[string]interface{}{
+			"http.method":      http.MethodGet,
+			"http.url":         url, // without query parameters, which may carry extra parameters
+			"http.status_code": statusCode,
+			"carta.attempts":   attempts,
+			"carta.shared":     shared,
+			"carta.duration":   time.Since(start).String(),
+		})
+		span.End(err)
+	}()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
 
-	if queryParams != nil {
-		req.URL.RawQuery = queryParams.Encode()
-	}
+	req.URL.RawQuery = c.encodeQuery(req.URL.Query(), queryParams)
 
 	req.Header.Add("authorization", fmt.Sprint("Bearer ", c.accessToken))
-	req.Header.Add("accept", "application/json")
+	req.Header.Add("accept", c.acceptHeader)
 
-	rawResponse, err := c.httpClient.Do(req)
+	c.dumpHeaders(ctx, req)
+
+	// identical requests in flight at the same time share a single upstream request, which keeps going
+	// as long as any of the callers waits for it
+	result, shared, err := c.flights.do(ctx, req.URL.String(), func(flightCtx context.Context) (flightResult, error) {
+		var result flightResult
+		flightReq := req.WithContext(flightCtx)
+
+		err := c.retry.do(flightCtx, func() error {
+			if result.attempts > 0 {
+				c.metrics.IncRetries(operation)
+			}
+			result.attempts++
+
+			var attemptErr error
+			result.statusCode, result.data, attemptErr = c.doAttempt(flightCtx, operation, flightReq)
+
+			return attemptErr
+		})
+
+		return result, err
+	})
+	statusCode = result.statusCode
+	attempts = result.attempts
 	if err != nil {
 		return err
 	}
 
+	// responses without content leave the response value untouched
+	if result.data == nil {
+		return nil
+	}
+
+	// numbers decoded into untyped values keep their exact representation instead of becoming float64.
+	// Unknown fields are tolerated on purpose, so fields Carta adds over time never break a sync;
+	// don't enable DisallowUnknownFields.
+	decoder := json.NewDecoder(bytes.NewReader(result.data))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&resourceResponse)
+
+	return err
+}
+
+// requestIdHeaders are the response headers Carta may use to identify a request, in order of preference.
+var requestIdHeaders = []string{"X-Request-Id", "X-Correlation-Id"}
+
+// responseRequestId returns the upstream request id of the response, or empty string if there is none.
+func responseRequestId(response *http.Response) string {
+	for _, header := range requestIdHeaders {
+		if requestId := response.Header.Get(header); requestId != "" {
+			return requestId
+		}
+	}
+
+	return ""
+}
+
+// requestTimeout returns the timeout of a single attempt of the operation.
+func (c *Client) requestTimeout(operation string) time.Duration {
+	if timeout, ok := c.operationTimeouts[operation]; ok {
+		return timeout
+	}
+
+	return c.timeout
+}
+
+// doAttempt sends the request once, marking transient failures as retryable. It returns the
+// response status code, zero when no response was received, and the response body.
+func (c *Client) doAttempt(ctx context.Context, operation string, req *http.Request) (int, []byte, error) {
+	trial, err := c.breaker.allow()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	record := func(failed bool) {
+		c.breaker.record(failed)
+		trial = false
+	}
+	defer func() {
+		if trial {
+			c.breaker.abandon()
+		}
+	}()
+
+	if err := c.inFlight.acquire(ctx); err != nil {
+		return 0, nil, err
+	}
+	defer c.inFlight.release()
+
+	if timeout := c.requestTimeout(operation); timeout > 0 {
+		attemptCtx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+
+		req = req.WithContext(attemptCtx)
+	}
+
+	c.metrics.IncRequests(operation)
+	start := time.Now()
+	rawResponse, err := c.httpClient.Do(req)
+	c.metrics.ObserveLatency(operation, time.Since(start))
+	if err != nil {
+		c.metrics.IncErrors(operation, 0)
+
+		// cancelled syncs say nothing about the health of the Carta API
+		if ctx.Err() != nil {
+			return 0, nil, err
+		}
+
+		record(true)
+		if isTimeout(err) && !c.retry.retryTimeouts {
+			return 0, nil, err
+		}
+
+		return 0, nil, &retryableError{err: err}
+	}
+
 	defer rawResponse.Body.Close()
 
+	record(rawResponse.StatusCode >= http.StatusInternalServerError)
+	c.deprecation.observe(c.logLevels.Logger(ctx, LogComponentClient), c.baseURL, rawResponse.Header)
+
+	requestId := responseRequestId(rawResponse)
+	c.logLevels.Logger(ctx, LogComponentClient).Debug(
+		"carta: received response",
+		zap.String("url", req.URL.String()),
+		zap.Int("status_code", rawResponse.StatusCode),
+		zap.String("request_id", requestId),
+	)
+
 	if rawResponse.StatusCode >= 300 {
-		return status.Error(codes.Code(rawResponse.StatusCode), "Request failed")
+		c.metrics.IncErrors(operation, rawResponse.StatusCode)
+
+		message := "Request failed"
+		if description := parseErrorResponse(rawResponse.Body).describe(); description != "" {
+			message = fmt.Sprintf("%s: %s", message, description)
+		}
+
+		if requestId != "" {
+			message = fmt.Sprintf("%s (carta request id: %s)", message, requestId)
+		}
+
+		err := status.Error(codes.Code(rawResponse.StatusCode), message)
+		if rawResponse.StatusCode == http.StatusTooManyRequests || rawResponse.StatusCode >= http.StatusInternalServerError {
+			return rawResponse.StatusCode, nil, &retryableError{err: err}
+		}
+
+		return rawResponse.StatusCode, nil, err
 	}
 
-	if err := json.NewDecoder(rawResponse.Body).Decode(&resourceResponse); err != nil {
-		return err
+	// responses without content have no body to decode
+	if rawResponse.StatusCode == http.StatusNoContent || rawResponse.StatusCode == http.StatusResetContent {
+		return rawResponse.StatusCode, nil, nil
 	}
 
-	return nil
+	var body io.Reader = rawResponse.Body
+	if c.maxResponseSize > 0 {
+		body = newLimitedBodyReader(rawResponse.Body, c.maxResponseSize)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		// a connection dropped mid-body leaves a truncated response, which another attempt may complete
+		if ctx.Err() == nil && isTruncatedBody(err) && (!isTimeout(err) || c.retry.retryTimeouts) {
+			return rawResponse.StatusCode, nil, &retryableError{err: fmt.Errorf("carta: truncated response body: %w", err)}
+		}
+
+		return rawResponse.StatusCode, nil, err
+	}
+
+	// a body cut short without a read error, e.g. a closed connection without content length, is only
+	// noticed as incomplete JSON
+	if isTruncatedJSON(data) {
+		return rawResponse.StatusCode, nil, &retryableError{err: fmt.Errorf("carta: truncated response body: %w", io.ErrUnexpectedEOF)}
+	}
+
+	// keep an empty body distinct from a response without content
+	if data == nil {
+		data = []byte{}
+	}
+
+	return rawResponse.StatusCode, data, nil
 }
@@ -0,0 +1,456 @@
+package carta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testAccessToken is the access token of test clients, distinct enough to be searched for in logs.
+const testAccessToken = "test-access-token-4f1c"
+
+// newTestClient returns a client sending its requests to a test server serving the handler. Failed
+// requests aren't retried unless the options configure retries.
+func newTestClient(t *testing.T, handler http.Handler, opts ...ClientOption) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewClient(
+		testAccessToken,
+		server.Client(),
+		append([]ClientOption{WithBaseURL(server.URL), WithRetry(0, time.Millisecond, time.Millisecond)}, opts...)...,
+	)
+}
+
+// roundTripFunc is an http.RoundTripper calling the function, for transport failures a test server can't produce.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// pageServer serves a single issuer page with the given next page token.
+func pageServer(next string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issuers" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuers":       []map[string]string{{"id": "acme"}},
+			"nextPageToken": next,
+		})
+	})
+}
+
+func TestTerminalPageTokens(t *testing.T) {
+	for _, tc := range []struct {
+		next string
+		opts []ClientOption
+		want string
+	}{
+		{next: "", want: ""},
+		{next: "null", want: ""},
+		{next: "0", want: ""},
+		{next: "page-2", want: "page-2"},
+		{next: "end", opts: []ClientOption{WithTerminalPageTokens("end")}, want: ""},
+		// configured sentinels replace the default ones
+		{next: "null", opts: []ClientOption{WithTerminalPageTokens("end")}, want: "null"},
+	} {
+		client := newTestClient(t, pageServer(tc.next), tc.opts...)
+
+		issuers, next, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10})
+		if err != nil {
+			t.Fatalf("GetIssuers() with next page token %q error = %v", tc.next, err)
+		}
+
+		if len(issuers) != 1 {
+			t.Errorf("GetIssuers() with next page token %q returned %d issuers, want 1", tc.next, len(issuers))
+		}
+
+		if next != tc.want {
+			t.Errorf("GetIssuers() with next page token %q returned next %q, want %q", tc.next, next, tc.want)
+		}
+	}
+}
+
+func TestRepeatedPageTokenEndsPages(t *testing.T) {
+	client := newTestClient(t, pageServer("page-2"))
+
+	if _, next, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10, After: "page-2"}); err != nil || next != "" {
+		t.Fatalf("GetIssuers() = next %q, %v, want the last page when the token repeats", next, err)
+	}
+}
+
+// slowServer delays its answer by the delay, or until the request is abandoned. It counts the
+// delayed requests it receives, leaving out page size limit discovery.
+func slowServer(delay time.Duration, handler http.Handler, received *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limits" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(received, 1)
+
+		select {
+		case <-time.After(delay):
+			handler.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// recordedRequest is a request received by a requestRecorder.
+type recordedRequest struct {
+	escapedPath string
+	rawQuery    string
+	query       url.Values
+	header      http.Header
+}
+
+// requestRecorder records the requests it receives before answering them with the handler.
+type requestRecorder struct {
+	mtx      sync.Mutex
+	handler  http.Handler
+	received []recordedRequest
+}
+
+func (r *requestRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mtx.Lock()
+	r.received = append(r.received, recordedRequest{
+		escapedPath: req.URL.EscapedPath(),
+		rawQuery:    req.URL.RawQuery,
+		query:       req.URL.Query(),
+		header:      req.Header.Clone(),
+	})
+	r.mtx.Unlock()
+
+	r.handler.ServeHTTP(w, req)
+}
+
+// requests returns the requests received so far, leaving out page size limit discovery.
+func (r *requestRecorder) requests() []recordedRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var requests []recordedRequest
+	for _, req := range r.received {
+		if req.escapedPath != "/limits" {
+			requests = append(requests, req)
+		}
+	}
+
+	return requests
+}
+
+func TestExtraQueryParams(t *testing.T) {
+	recorder := &requestRecorder{handler: pageServer("")}
+	client := newTestClient(t, recorder, WithExtraQueryParams(map[string]string{
+		"feature":   "beta",
+		"pageSize":  "999",
+		"pageToken": "injected",
+	}))
+
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10, After: "page-2"}); err != nil {
+		t.Fatalf("GetIssuers() error = %v", err)
+	}
+
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{}); err != nil {
+		t.Fatalf("GetIssuers() error = %v", err)
+	}
+
+	requests := recorder.requests()
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(requests))
+	}
+
+	paged := requests[0].query
+	if paged.Get("feature") != "beta" {
+		t.Errorf("query %q misses the extra parameter", requests[0].rawQuery)
+	}
+
+	if paged.Get("pageSize") != "10" || paged.Get("pageToken") != "page-2" || len(paged["pageSize"]) != 1 || len(paged["pageToken"]) != 1 {
+		t.Errorf("query %q, want the pagination parameters of the request to take precedence", requests[0].rawQuery)
+	}
+
+	// parameters the request doesn't set are added from the extra parameters
+	unpaged := requests[1].query
+	if unpaged.Get("feature") != "beta" || unpaged.Get("pageSize") != "999" || unpaged.Get("pageToken") != "injected" {
+		t.Errorf("query %q, want every extra parameter on a request without pagination", requests[1].rawQuery)
+	}
+}
+
+func TestCountsReadTotalCounts(t *testing.T) {
+	totals := map[string]int{"/issuers": 1200, "/portfolios": 34, "/investors/firms": 5}
+	recorder := &requestRecorder{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		total, ok := totals[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"totalCount": total, "nextPageToken": "1"})
+	})}
+	client := newTestClient(t, recorder)
+
+	counts, err := client.Counts(context.Background())
+	if err != nil {
+		t.Fatalf("Counts() error = %v", err)
+	}
+
+	if want := (Counts{Issuers: 1200, Portfolios: 34, Investors: 5}); counts != want {
+		t.Errorf("Counts() = %+v, want %+v", counts, want)
+	}
+
+	for _, req := range recorder.requests() {
+		if req.query.Get("pageSize") != "1" {
+			t.Errorf("request to %s asked for page size %q, want single item pages", req.escapedPath, req.query.Get("pageSize"))
+		}
+	}
+}
+
+func TestCountsFailWithListing(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/portfolios" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"totalCount": 1}`))
+	}))
+
+	if _, err := client.Counts(context.Background()); !IsAccessDenied(err) {
+		t.Fatalf("Counts() error = %v, want the portfolio listing's access denied error", err)
+	}
+}
+
+func TestGetChangesPage(t *testing.T) {
+	recorder := &requestRecorder{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"changes": [
+				{"resourceType": "issuer", "resourceId": "acme", "changeType": "created", "changedAt": "2024-03-01T10:00:00Z"},
+				{"resourceType": "portfolio", "resourceId": "growth", "changeType": "UPDATED", "changedAt": "2024-03-01T11:00:00Z"},
+				{"resourceType": "issuer", "resourceId": "globex", "changeType": " Deleted ", "changedAt": "2024-03-01T12:00:00Z"}
+			],
+			"nextPageToken": "page-2"
+		}`))
+	})}
+	client := newTestClient(t, recorder)
+
+	since := time.Date(2024, 3, 1, 11, 0, 0, 0, time.FixedZone("CET", 3600))
+	changes, next, err := client.GetChanges(context.Background(), since, PaginationParams{Size: 50})
+	if err != nil {
+		t.Fatalf("GetChanges() error = %v", err)
+	}
+
+	if next != "page-2" {
+		t.Errorf("GetChanges() next = %q, want page-2", next)
+	}
+
+	want := []Change{
+		{ResourceType: "issuer", ResourceId: "acme", ChangeType: ChangeTypeCreated, ChangedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{ResourceType: "portfolio", ResourceId: "growth", ChangeType: ChangeTypeUpdated, ChangedAt: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{ResourceType: "issuer", ResourceId: "globex", ChangeType: ChangeTypeDeleted, ChangedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("GetChanges() returned %d changes, want %d", len(changes), len(want))
+	}
+
+	for i := range want {
+		if changes[i].ResourceType != want[i].ResourceType || changes[i].ResourceId != want[i].ResourceId ||
+			changes[i].ChangeType != want[i].ChangeType || !changes[i].ChangedAt.Equal(want[i].ChangedAt) {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+
+	requests := recorder.requests()
+	if len(requests) != 1 || requests[0].escapedPath != "/changes" {
+		t.Fatalf("requests = %+v, want a single change feed request", requests)
+	}
+
+	if got := requests[0].query.Get("since"); got != "2024-03-01T10:00:00Z" {
+		t.Errorf("since = %q, want the time in UTC", got)
+	}
+}
+
+func TestGetChangesWithoutSince(t *testing.T) {
+	recorder := &requestRecorder{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"changes": []}`))
+	})}
+	client := newTestClient(t, recorder)
+
+	changes, next, err := client.GetChanges(context.Background(), time.Time{}, PaginationParams{})
+	if err != nil || len(changes) != 0 || next != "" {
+		t.Fatalf("GetChanges() = %v, %q, %v, want an empty last page", changes, next, err)
+	}
+
+	if requests := recorder.requests(); len(requests) != 1 || requests[0].query.Has("since") {
+		t.Errorf("requests = %+v, want no since parameter for a zero time", requests)
+	}
+}
+
+func TestAcceptHeader(t *testing.T) {
+	for _, tc := range []struct {
+		opts []ClientOption
+		want string
+	}{
+		{want: "application/json"},
+		{opts: []ClientOption{WithAcceptHeader("application/vnd.carta.v2+json")}, want: "application/vnd.carta.v2+json"},
+	} {
+		recorder := &requestRecorder{handler: pageServer("")}
+		client := newTestClient(t, recorder, tc.opts...)
+
+		if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10}); err != nil {
+			t.Fatalf("GetIssuers() error = %v", err)
+		}
+
+		for _, req := range recorder.requests() {
+			if got := req.header.Values("Accept"); len(got) != 1 || got[0] != tc.want {
+				t.Errorf("request to %s sent accept headers %q, want %q", req.escapedPath, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestResourceIdsEscapedAsPathSegments(t *testing.T) {
+	recorder := &requestRecorder{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": "acme"})
+	})}
+	client := newTestClient(t, recorder)
+
+	for _, tc := range []struct {
+		id   string
+		want string
+	}{
+		{"acme", "/issuers/acme"},
+		{"acme corp", "/issuers/acme%20corp"},
+		{"acme/../portfolios", "/issuers/acme%2F..%2Fportfolios"},
+		{"acme?x=1#y", "/issuers/acme%3Fx=1%23y"},
+	} {
+		if _, err := client.GetIssuer(context.Background(), tc.id); err != nil {
+			t.Fatalf("GetIssuer(%q) error = %v", tc.id, err)
+		}
+
+		requests := recorder.requests()
+		if got := requests[len(requests)-1]; got.escapedPath != tc.want || got.rawQuery != "" {
+			t.Errorf("GetIssuer(%q) requested %s?%s, want %s", tc.id, got.escapedPath, got.rawQuery, tc.want)
+		}
+	}
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	var received int32
+	client := newTestClient(
+		t,
+		slowServer(100*time.Millisecond, echoIssuer, &received),
+		WithRequestTimeout(20*time.Millisecond),
+		WithOperationTimeouts(map[string]time.Duration{"GetIssuers": time.Second, "GetIssuerContact": 0}),
+	)
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); !isTimeout(err) {
+		t.Errorf("GetIssuer() error = %v, want the request timeout to expire", err)
+	}
+
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10}); err != nil {
+		t.Errorf("GetIssuers() error = %v, want its longer operation timeout to apply", err)
+	}
+
+	// a zero override disables the timeout of the operation
+	if _, err := client.GetIssuerContact(context.Background(), "acme"); err != nil {
+		t.Errorf("GetIssuerContact() error = %v, want no timeout", err)
+	}
+}
+
+func TestResponsesWithoutContent(t *testing.T) {
+	for _, statusCode := range []int{http.StatusNoContent, http.StatusResetContent} {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(statusCode)
+		}))
+
+		issuers, next, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10})
+		if err != nil {
+			t.Fatalf("GetIssuers() answered with %d error = %v", statusCode, err)
+		}
+
+		if len(issuers) != 0 || next != "" {
+			t.Errorf("GetIssuers() answered with %d = %d issuers, next %q, want an empty last page", statusCode, len(issuers), next)
+		}
+	}
+
+	// an empty body where content is expected is still malformed
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10}); err == nil {
+		t.Error("GetIssuers() with an empty 200 response succeeded, want a decoding error")
+	}
+}
+
+func TestDecodingToleratesUnknownFields(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"issuers": [{"id": "acme", "legalName": "Acme Inc.", "fundingStage": {"round": "C"}, "tags": ["x"]}],
+			"nextPageToken": "page-2",
+			"apiVersion": 3
+		}`))
+	}))
+
+	issuers, next, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10})
+	if err != nil {
+		t.Fatalf("GetIssuers() error = %v, want unknown fields to be ignored", err)
+	}
+
+	if len(issuers) != 1 || issuers[0].Id != "acme" || issuers[0].Name != "Acme Inc." || next != "page-2" {
+		t.Errorf("GetIssuers() = %+v, next %q, want the known fields decoded", issuers, next)
+	}
+}
+
+func TestQueryParametersEscaped(t *testing.T) {
+	recorder := &requestRecorder{handler: pageServer("")}
+	client := newTestClient(t, recorder, WithExtraQueryParams(map[string]string{
+		"filter": "name=a b&stage=c",
+		"label":  "café ☕",
+	}))
+
+	const token = "tok en/é&pageSize=1#x"
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10, After: token}); err != nil {
+		t.Fatalf("GetIssuers() error = %v", err)
+	}
+
+	requests := recorder.requests()
+	if len(requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(requests))
+	}
+
+	query := requests[0]
+	if strings.ContainsAny(query.rawQuery, " #") || strings.IndexFunc(query.rawQuery, func(r rune) bool { return r > 127 }) >= 0 {
+		t.Errorf("query %q holds unescaped characters", query.rawQuery)
+	}
+
+	want := url.Values{
+		"filter":    {"name=a b&stage=c"},
+		"label":     {"café ☕"},
+		"pageSize":  {"10"},
+		"pageToken": {token},
+	}
+	if !reflect.DeepEqual(query.query, want) {
+		t.Errorf("query %q decodes to %v, want %v", query.rawQuery, query.query, want)
+	}
+}
+
+func TestEncodeQueryKeepsEndpointQuery(t *testing.T) {
+	client := NewClient(testAccessToken, nil, WithExtraQueryParams(map[string]string{"view": "full"}))
+
+	endpointQuery := url.Values{"view": {"summary"}, "include": {"a b"}}
+	got := client.encodeQuery(endpointQuery, url.Values{"pageSize": {"10"}})
+
+	if want := "include=a+b&pageSize=10&view=full"; got != want {
+		t.Errorf("encodeQuery() = %q, want %q", got, want)
+	}
+}
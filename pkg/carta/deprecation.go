@@ -0,0 +1,83 @@
+package carta
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deprecationNotice warns once when Carta signals, through the Deprecation or Sunset response
+// headers, that the API version in use is deprecated.
+type deprecationNotice struct {
+	once sync.Once
+}
+
+// observe logs a warning for the first response carrying a deprecation signal.
+func (d *deprecationNotice) observe(logger *zap.Logger, baseURL string, header http.Header) {
+	deprecated, deprecatedAt := parseDeprecationHeader(header.Get("Deprecation"))
+	sunset, sunsetAt := parseHTTPDate(header.Get("Sunset"))
+	if !deprecated && !sunset {
+		return
+	}
+
+	d.once.Do(func() {
+		fields := []zap.Field{zap.String("base_url", baseURL)}
+		if !deprecatedAt.IsZero() {
+			fields = append(fields, zap.Time("deprecated_at", deprecatedAt))
+		}
+		if sunset {
+			fields = append(fields, zap.Time("sunset_at", sunsetAt))
+		}
+
+		logger.Warn(
+			"carta: the Carta API version in use is deprecated, plan a migration before it is retired",
+			fields...,
+		)
+	})
+}
+
+// parseDeprecationHeader parses both forms of the Deprecation header: the structured "@<unix seconds>"
+// date or the boolean "true", and the HTTP-date of earlier drafts.
+func parseDeprecationHeader(value string) (bool, time.Time) {
+	value = strings.TrimSpace(value)
+
+	switch {
+	case value == "":
+		return false, time.Time{}
+	case strings.EqualFold(value, "true") || strings.EqualFold(value, "?1"):
+		return true, time.Time{}
+	case strings.EqualFold(value, "false") || strings.EqualFold(value, "?0"):
+		return false, time.Time{}
+	case strings.HasPrefix(value, "@"):
+		seconds, err := strconv.ParseInt(strings.TrimPrefix(value, "@"), 10, 64)
+		if err != nil {
+			return true, time.Time{}
+		}
+
+		return true, time.Unix(seconds, 0).UTC()
+	}
+
+	// an unparseable value still signals a deprecation
+	_, at := parseHTTPDate(value)
+
+	return true, at
+}
+
+// parseHTTPDate parses a header holding an HTTP-date, reporting whether the header was set.
+func parseHTTPDate(value string) (bool, time.Time) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, time.Time{}
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return true, time.Time{}
+	}
+
+	return true, at.UTC()
+}
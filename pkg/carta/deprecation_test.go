@@ -0,0 +1,89 @@
+package carta
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+const deprecationWarning = "carta: the Carta API version in use is deprecated, plan a migration before it is retired"
+
+// headerServer answers every request with an issuer and the given response headers.
+func headerServer(headers map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		echoIssuer.ServeHTTP(w, r)
+	})
+}
+
+func TestDeprecationWarnsOnce(t *testing.T) {
+	for _, tc := range []struct {
+		headers      map[string]string
+		deprecatedAt float64
+		sunsetAt     float64
+	}{
+		{headers: map[string]string{"Deprecation": "true"}},
+		{headers: map[string]string{"Deprecation": "@1767225600"}, deprecatedAt: 1767225600},
+		{headers: map[string]string{"Deprecation": "Thu, 01 Jan 2026 00:00:00 GMT"}, deprecatedAt: 1767225600},
+		{headers: map[string]string{"Sunset": "Fri, 01 Jan 2027 00:00:00 GMT"}, sunsetAt: 1798761600},
+	} {
+		ctx, logs := newLogContext()
+		client := newTestClient(t, headerServer(tc.headers))
+
+		for _, id := range []string{"acme", "globex", "initech"} {
+			if _, err := client.GetIssuer(ctx, id); err != nil {
+				t.Fatalf("GetIssuer(%q) error = %v", id, err)
+			}
+		}
+
+		entries := logs.entries(t, deprecationWarning)
+		if len(entries) != 1 {
+			t.Fatalf("headers %v: logged %d deprecation warnings, want 1", tc.headers, len(entries))
+		}
+
+		// times are logged as epoch seconds, absent fields leave zero
+		if got, _ := entries[0]["deprecated_at"].(float64); got != tc.deprecatedAt {
+			t.Errorf("headers %v: deprecated at %v, want %v", tc.headers, got, tc.deprecatedAt)
+		}
+
+		if got, _ := entries[0]["sunset_at"].(float64); got != tc.sunsetAt {
+			t.Errorf("headers %v: sunset at %v, want %v", tc.headers, got, tc.sunsetAt)
+		}
+	}
+}
+
+func TestNoDeprecationWarningWithoutSignal(t *testing.T) {
+	for _, headers := range []map[string]string{nil, {"Deprecation": "false"}, {"Deprecation": "?0"}} {
+		ctx, logs := newLogContext()
+		client := newTestClient(t, headerServer(headers))
+
+		if _, err := client.GetIssuer(ctx, "acme"); err != nil {
+			t.Fatalf("GetIssuer() error = %v", err)
+		}
+
+		if entries := logs.entries(t, deprecationWarning); len(entries) != 0 {
+			t.Errorf("headers %v: logged %d deprecation warnings, want none", headers, len(entries))
+		}
+	}
+}
+
+func TestParseDeprecationHeader(t *testing.T) {
+	for _, tc := range []struct {
+		value      string
+		deprecated bool
+		at         time.Time
+	}{
+		{"", false, time.Time{}},
+		{"?1", true, time.Time{}},
+		{"@not-a-date", true, time.Time{}},
+		{"someday", true, time.Time{}},
+		{"@0", true, time.Unix(0, 0).UTC()},
+	} {
+		deprecated, at := parseDeprecationHeader(tc.value)
+		if deprecated != tc.deprecated || !at.Equal(tc.at) {
+			t.Errorf("parseDeprecationHeader(%q) = %t, %v, want %t, %v", tc.value, deprecated, at, tc.deprecated, tc.at)
+		}
+	}
+}
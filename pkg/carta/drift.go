@@ -0,0 +1,52 @@
+package carta
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// driftDetector compares the total count reported by successive pages of a listing, warning when
+// the underlying set changed during the walk and results may be inconsistent.
+// A nil detector does nothing.
+type driftDetector struct {
+	mtx    sync.Mutex
+	totals map[string]int
+}
+
+func newDriftDetector() *driftDetector {
+	return &driftDetector{
+		totals: make(map[string]int),
+	}
+}
+
+// observe records the total count of a page of the listing at endpoint, the first page (or the first
+// page seen when resuming) sets the baseline.
+func (d *driftDetector) observe(logger *zap.Logger, endpoint string, after string, total int) {
+	if d == nil {
+		return
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	baseline, ok := d.totals[endpoint]
+	if after == "" || !ok {
+		d.totals[endpoint] = total
+		return
+	}
+
+	if baseline == total {
+		return
+	}
+
+	logger.Warn(
+		"carta: total count changed during paginated listing, results may be inconsistent",
+		zap.String("url", endpoint),
+		zap.Int("initial_total", baseline),
+		zap.Int("current_total", total),
+	)
+
+	// warn once per change
+	d.totals[endpoint] = total
+}
@@ -0,0 +1,78 @@
+package carta
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// totalsServer serves issuer pages reporting the total count for the requested page token.
+func totalsServer(totals map[string]int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issuers" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuers":    []map[string]string{{"id": "acme"}},
+			"totalCount": totals[r.URL.Query().Get("pageToken")],
+		})
+	})
+}
+
+func TestPaginationDriftWarnings(t *testing.T) {
+	const warning = "carta: total count changed during paginated listing, results may be inconsistent"
+
+	for _, tc := range []struct {
+		name     string
+		enabled  bool
+		totals   map[string]int
+		warnings int
+	}{
+		{name: "shrinking total", enabled: true, totals: map[string]int{"": 10, "page-2": 8, "page-3": 8}, warnings: 1},
+		{name: "stable total", enabled: true, totals: map[string]int{"": 10, "page-2": 10, "page-3": 10}},
+		{name: "disabled", totals: map[string]int{"": 10, "page-2": 8, "page-3": 8}},
+	} {
+		ctx, logs := newLogContext()
+		client := newTestClient(t, totalsServer(tc.totals), WithPaginationDriftDetection(tc.enabled))
+
+		for _, after := range []string{"", "page-2", "page-3"} {
+			if _, _, err := client.GetIssuers(ctx, PaginationParams{Size: 1, After: after}); err != nil {
+				t.Fatalf("%s: GetIssuers(%q) error = %v", tc.name, after, err)
+			}
+		}
+
+		entries := logs.entries(t, warning)
+		if len(entries) != tc.warnings {
+			t.Fatalf("%s: logged %d drift warnings, want %d", tc.name, len(entries), tc.warnings)
+		}
+
+		if tc.warnings > 0 && (entries[0]["initial_total"] != 10.0 || entries[0]["current_total"] != 8.0) {
+			t.Errorf("%s: warning %v, want the initial and current totals", tc.name, entries[0])
+		}
+	}
+}
+
+func TestPaginationDriftBaselineResetsOnFirstPage(t *testing.T) {
+	ctx, logs := newLogContext()
+	totals := map[string]int{"": 10, "page-2": 10}
+	client := newTestClient(t, totalsServer(totals), WithPaginationDriftDetection(true))
+
+	for _, after := range []string{"", "page-2"} {
+		if _, _, err := client.GetIssuers(ctx, PaginationParams{Size: 1, After: after}); err != nil {
+			t.Fatalf("GetIssuers(%q) error = %v", after, err)
+		}
+	}
+
+	// a new walk of the listing starts over from its own first page
+	totals[""], totals["page-2"] = 8, 8
+	for _, after := range []string{"", "page-2"} {
+		if _, _, err := client.GetIssuers(ctx, PaginationParams{Size: 1, After: after}); err != nil {
+			t.Fatalf("GetIssuers(%q) error = %v", after, err)
+		}
+	}
+
+	if entries := logs.entries(t, "carta: total count changed during paginated listing, results may be inconsistent"); len(entries) != 0 {
+		t.Errorf("logged %d drift warnings across two walks, want none", len(entries))
+	}
+}
@@ -0,0 +1,89 @@
+package carta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxErrorBodySize caps how much of an error response body is read to find the Carta error code.
+const maxErrorBodySize = 64 << 10 // 64 KiB
+
+// errorResponse is the body Carta returns along with a failed request.
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorCodeMessages maps known Carta error codes to messages telling operators what to do about them.
+var errorCodeMessages = map[string]string{
+	"INVALID_TOKEN":      "the access token is invalid or expired, generate a new token in Carta",
+	"INSUFFICIENT_SCOPE": "the access token lacks the required scopes, grant read access to issuers, portfolios and investors",
+	"QUOTA_EXCEEDED":     "the Carta API quota is exhausted, retry later or lower the sync frequency",
+	"RATE_LIMITED":       "too many requests were sent to Carta, lower the concurrency or retry later",
+	"RESOURCE_NOT_FOUND": "the resource no longer exists or is not visible to the access token",
+}
+
+// IsAccessDenied reports whether the request failed because the access token isn't allowed to see the resource.
+func IsAccessDenied(err error) bool {
+	return status.Code(err) == codes.Code(http.StatusForbidden)
+}
+
+// IsNotFound reports whether the request failed because the resource doesn't exist, which retrying won't change.
+func IsNotFound(err error) bool {
+	return status.Code(err) == codes.Code(http.StatusNotFound)
+}
+
+// IsTransient reports whether the request failed for a reason that may go away later, e.g. rate limiting,
+// server errors or network failures, even though the retries were exhausted.
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || isTimeout(err) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	code := status.Code(err)
+	return code == codes.Code(http.StatusTooManyRequests) || code >= codes.Code(http.StatusInternalServerError)
+}
+
+// parseErrorResponse reads the Carta error code and message from a failed response body,
+// returning a zero value when the body isn't a Carta error.
+func parseErrorResponse(body io.Reader) errorResponse {
+	var errResponse errorResponse
+	if err := json.NewDecoder(io.LimitReader(body, maxErrorBodySize)).Decode(&errResponse); err != nil {
+		return errorResponse{}
+	}
+
+	errResponse.Code = strings.ToUpper(strings.TrimSpace(errResponse.Code))
+
+	return errResponse
+}
+
+// describe returns an actionable description of the error, falling back to the raw code and message
+// for codes that aren't known.
+func (e errorResponse) describe() string {
+	if e.Code == "" {
+		return e.Message
+	}
+
+	if message, ok := errorCodeMessages[e.Code]; ok {
+		return fmt.Sprintf("%s (carta error code: %s)", message, e.Code)
+	}
+
+	if e.Message != "" {
+		return fmt.Sprintf("%s (carta error code: %s)", e.Message, e.Code)
+	}
+
+	return fmt.Sprintf("carta error code: %s", e.Code)
+}
@@ -0,0 +1,48 @@
+package carta
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorCodeMessages(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		want string
+	}{
+		{`{"code": "INVALID_TOKEN", "message": "jwt expired"}`, "Request failed: " + errorCodeMessages["INVALID_TOKEN"] + " (carta error code: INVALID_TOKEN)"},
+		// codes are matched regardless of case and surrounding space
+		{`{"code": " insufficient_scope "}`, "Request failed: " + errorCodeMessages["INSUFFICIENT_SCOPE"] + " (carta error code: INSUFFICIENT_SCOPE)"},
+		{`{"code": "LEGAL_HOLD", "message": "issuer is on legal hold"}`, "Request failed: issuer is on legal hold (carta error code: LEGAL_HOLD)"},
+		{`{"code": "LEGAL_HOLD"}`, "Request failed: carta error code: LEGAL_HOLD"},
+		{`{"message": "no such issuer"}`, "Request failed: no such issuer"},
+		{`<html>Forbidden</html>`, "Request failed"},
+		{``, "Request failed"},
+	} {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(tc.body))
+		}))
+
+		_, err := client.GetIssuer(context.Background(), "acme")
+		if status.Code(err) != codes.Code(http.StatusForbidden) || !IsAccessDenied(err) {
+			t.Errorf("body %q: error %v, want the response status as error code", tc.body, err)
+		}
+
+		if got := status.Convert(err).Message(); got != tc.want {
+			t.Errorf("body %q: message %q, want %q", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestErrorBodyReadIsCapped(t *testing.T) {
+	body := `{"message": "` + strings.Repeat("x", maxErrorBodySize) + `"}`
+	if got := parseErrorResponse(strings.NewReader(body)); got != (errorResponse{}) {
+		t.Errorf("parseErrorResponse() of an oversized body = %+v, want no error details", got)
+	}
+}
@@ -0,0 +1,45 @@
+package carta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const defaultMaxResponseSize int64 = 32 << 20 // 32 MiB
+
+// ErrResponseTooLarge is returned when a Carta response body exceeds the configured maximum size.
+var ErrResponseTooLarge = errors.New("carta: response body exceeds maximum size")
+
+// limitedBodyReader reads up to limit bytes and fails instead of silently truncating larger bodies.
+type limitedBodyReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+func newLimitedBodyReader(r io.Reader, limit int64) *limitedBodyReader {
+	return &limitedBodyReader{r: r, limit: limit, remaining: limit}
+}
+
+func (l *limitedBodyReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// probe for data past the limit
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w (%d bytes)", ErrResponseTooLarge, l.limit)
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+
+	return n, err
+}
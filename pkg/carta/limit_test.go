@@ -0,0 +1,67 @@
+package carta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// issuerBody is an issuer response padded with whitespace to exactly size bytes.
+func issuerBody(size int) string {
+	body := `{"issuer": {"id": "acme"}}`
+
+	return body + strings.Repeat(" ", size-len(body))
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	const limit = 256
+
+	for _, tc := range []struct {
+		name     string
+		size     int
+		tooLarge bool
+	}{
+		{"below the limit", limit - 1, false},
+		{"at the limit", limit, false},
+		{"over the limit", limit + 1, true},
+		{"far over the limit", 64 * limit, true},
+	} {
+		var requests atomic.Int32
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			_, _ = w.Write([]byte(issuerBody(tc.size)))
+		}), WithMaxResponseSize(limit), WithRetry(3, time.Millisecond, time.Millisecond))
+
+		issuer, err := client.GetIssuer(context.Background(), "acme")
+		if tc.tooLarge {
+			if !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("%s: GetIssuer() error = %v, want ErrResponseTooLarge", tc.name, err)
+			}
+
+			// the same body would come back again, an oversized response isn't retried
+			if requests.Load() != 1 {
+				t.Errorf("%s: sent %d requests, want 1", tc.name, requests.Load())
+			}
+
+			continue
+		}
+
+		if err != nil || issuer.Id != "acme" {
+			t.Errorf("%s: GetIssuer() = %+v, %v, want the issuer", tc.name, issuer, err)
+		}
+	}
+}
+
+func TestResponseSizeLimitDisabled(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(issuerBody(1 << 10)))
+	}), WithMaxResponseSize(0))
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); err != nil {
+		t.Fatalf("GetIssuer() error = %v, a zero maximum disables the limit", err)
+	}
+}
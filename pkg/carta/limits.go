@@ -0,0 +1,75 @@
+package carta
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// limitsDiscoveryTimeout bounds the discovery of the API limits, which doesn't run under the context
+// of the request that triggered it.
+const limitsDiscoveryTimeout = 30 * time.Second
+
+// pageSizeLimit discovers the page size cap of the Carta API once per client, falling back to
+// the configured default when the limits can't be fetched.
+type pageSizeLimit struct {
+	mtx      sync.Mutex
+	done     bool
+	fallback int
+	limit    int
+}
+
+// maxPageSize returns the largest page size requests may ask for, zero means there is no known cap.
+// The limits are fetched under a context of their own, so a cancelled request doesn't leave the
+// client on the fallback for good, and discovery is tried again after a transient failure.
+func (c *Client) maxPageSize(ctx context.Context) int {
+	c.pageLimit.mtx.Lock()
+	defer c.pageLimit.mtx.Unlock()
+
+	if c.pageLimit.done {
+		return c.pageLimit.limit
+	}
+
+	discoveryCtx, cancel := context.WithTimeout(ctxzap.ToContext(context.Background(), ctxzap.Extract(ctx)), limitsDiscoveryTimeout)
+	defer cancel()
+
+	var limitsResponse LimitsResponse
+	err := c.doRequest(discoveryCtx, "GetLimits", LimitsBaseURL, &limitsResponse, nil)
+	if err != nil {
+		c.logLevels.Logger(ctx, LogComponentPagination).Debug(
+			"carta: unable to discover API limits, using the configured max page size",
+			zap.Int("max_page_size", c.pageLimit.fallback),
+			zap.Error(err),
+		)
+
+		c.pageLimit.done = !IsTransient(err)
+		c.pageLimit.limit = c.pageLimit.fallback
+
+		return c.pageLimit.limit
+	}
+
+	c.pageLimit.done = true
+	c.pageLimit.limit = c.pageLimit.fallback
+	if limitsResponse.MaxPageSize > 0 {
+		c.pageLimit.limit = limitsResponse.MaxPageSize
+	}
+
+	return c.pageLimit.limit
+}
+
+// clampPageSize lowers the requested page size to the page size cap of the API.
+func (c *Client) clampPageSize(ctx context.Context, queryParams url.Values) {
+	size, err := strconv.Atoi(queryParams.Get("pageSize"))
+	if err != nil {
+		return
+	}
+
+	if limit := c.maxPageSize(ctx); limit > 0 && size > limit {
+		queryParams.Set("pageSize", strconv.Itoa(limit))
+	}
+}
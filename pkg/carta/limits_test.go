@@ -0,0 +1,105 @@
+package carta
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// limitsServer serves the API limits and records the page sizes issuer listings ask for.
+type limitsServer struct {
+	mtx         sync.Mutex
+	limitStatus []int
+	pageSizes   []string
+}
+
+func (s *limitsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch r.URL.Path {
+	case "/limits":
+		if len(s.limitStatus) > 0 {
+			code := s.limitStatus[0]
+			s.limitStatus = s.limitStatus[1:]
+			w.WriteHeader(code)
+			return
+		}
+		_, _ = w.Write([]byte(`{"maxPageSize": 50}`))
+	case "/issuers":
+		s.pageSizes = append(s.pageSizes, r.URL.Query().Get("pageSize"))
+		_, _ = w.Write([]byte(`{"issuers": [], "nextPageToken": ""}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *limitsServer) requestedPageSizes() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return append([]string(nil), s.pageSizes...)
+}
+
+func TestPageSizeClampedToDiscoveredLimit(t *testing.T) {
+	s := &limitsServer{}
+	client := newTestClient(t, s)
+
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 100}); err != nil {
+		t.Fatalf("GetIssuers() error = %v", err)
+	}
+
+	if got := s.requestedPageSizes(); len(got) != 1 || got[0] != "50" {
+		t.Errorf("requested page sizes = %v, want [50]", got)
+	}
+}
+
+func TestLimitDiscoveryOutlivesCancelledRequest(t *testing.T) {
+	s := &limitsServer{}
+	client := newTestClient(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := client.GetIssuers(ctx, PaginationParams{Size: 100}); err == nil {
+		t.Fatal("GetIssuers() succeeded with a cancelled context")
+	}
+
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 100}); err != nil {
+		t.Fatalf("GetIssuers() error = %v", err)
+	}
+
+	if got := s.requestedPageSizes(); len(got) != 1 || got[0] != "50" {
+		t.Errorf("requested page sizes = %v, want [50]", got)
+	}
+}
+
+func TestLimitDiscoveryRetriedAfterTransientFailure(t *testing.T) {
+	s := &limitsServer{limitStatus: []int{http.StatusServiceUnavailable}}
+	client := newTestClient(t, s)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 100}); err != nil {
+			t.Fatalf("GetIssuers() error = %v", err)
+		}
+	}
+
+	if got := s.requestedPageSizes(); len(got) != 2 || got[0] != "100" || got[1] != "50" {
+		t.Errorf("requested page sizes = %v, want [100 50]", got)
+	}
+}
+
+func TestLimitDiscoveryFallsBackWithoutLimitsEndpoint(t *testing.T) {
+	s := &limitsServer{limitStatus: []int{http.StatusNotFound}}
+	client := newTestClient(t, s)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 100}); err != nil {
+			t.Fatalf("GetIssuers() error = %v", err)
+		}
+	}
+
+	if got := s.requestedPageSizes(); len(got) != 2 || got[0] != "100" || got[1] != "100" {
+		t.Errorf("requested page sizes = %v, want [100 100]", got)
+	}
+}
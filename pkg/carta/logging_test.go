@@ -0,0 +1,174 @@
+package carta
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logBuffer collects the JSON log lines of a test logger.
+type logBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.buf.String()
+}
+
+// entries returns the logged entries with the given message, decoded into their fields.
+func (b *logBuffer) entries(t *testing.T, message string) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(b.String()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to decode log line %q: %v", scanner.Text(), err)
+		}
+
+		if entry["msg"] == message {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// newLogContext returns a context carrying a logger that logs every level into the returned buffer.
+func newLogContext() (context.Context, *logBuffer) {
+	logs := &logBuffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(logs), zapcore.DebugLevel)
+
+	return ctxzap.ToContext(context.Background(), zap.New(core)), logs
+}
+
+// echoIssuer answers every request with the same issuer.
+var echoIssuer = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(`{"issuer": {"id": "acme"}}`))
+})
+
+func TestDebugHeadersNeverDumpAuthorization(t *testing.T) {
+	const dumpMessage = "carta: outgoing request headers"
+
+	for _, headers := range [][]string{
+		{"Accept", "Authorization"},
+		{"accept", "AUTHORIZATION", "authorization"},
+	} {
+		ctx, logs := newLogContext()
+		client := newTestClient(t, echoIssuer, WithDebugHeaders(headers...))
+
+		if _, err := client.GetIssuer(ctx, "acme"); err != nil {
+			t.Fatalf("GetIssuer() error = %v", err)
+		}
+
+		entries := logs.entries(t, dumpMessage)
+		if len(entries) != 1 {
+			t.Fatalf("debug headers %v: logged %d header dumps, want 1", headers, len(entries))
+		}
+
+		if entries[0]["Accept"] != defaultAcceptHeader {
+			t.Errorf("debug headers %v: dumped accept header %v, want %s", headers, entries[0]["Accept"], defaultAcceptHeader)
+		}
+
+		if _, ok := entries[0]["Authorization"]; ok {
+			t.Errorf("debug headers %v: the authorization header was dumped", headers)
+		}
+
+		if strings.Contains(logs.String(), testAccessToken) {
+			t.Errorf("debug headers %v: the access token was logged:\n%s", headers, logs.String())
+		}
+	}
+}
+
+func TestDumpHeadersSkipsAuthorizationSetDirectly(t *testing.T) {
+	ctx, logs := newLogContext()
+	client := newTestClient(t, echoIssuer)
+	// bypass the option's filtering, the dump itself must still leave the header out
+	client.debugHeaders = []string{"Authorization", "Accept"}
+
+	if _, err := client.GetIssuer(ctx, "acme"); err != nil {
+		t.Fatalf("GetIssuer() error = %v", err)
+	}
+
+	if strings.Contains(logs.String(), testAccessToken) {
+		t.Errorf("the access token was logged:\n%s", logs.String())
+	}
+}
+
+func TestNoHeaderDumpByDefault(t *testing.T) {
+	ctx, logs := newLogContext()
+	client := newTestClient(t, echoIssuer)
+
+	if _, err := client.GetIssuer(ctx, "acme"); err != nil {
+		t.Fatalf("GetIssuer() error = %v", err)
+	}
+
+	if entries := logs.entries(t, "carta: outgoing request headers"); len(entries) != 0 {
+		t.Errorf("logged %d header dumps without debug headers", len(entries))
+	}
+}
+
+func TestRequestIdInResponseLog(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   string
+	}{
+		{"X-Request-Id", "req-123"},
+		{"X-Correlation-Id", "corr-456"},
+		{"", ""},
+	} {
+		ctx, logs := newLogContext()
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.header != "" {
+				w.Header().Set(tc.header, tc.want)
+			}
+			w.WriteHeader(http.StatusForbidden)
+		}))
+
+		_, err := client.GetIssuer(ctx, "acme")
+		if err == nil {
+			t.Fatal("GetIssuer() succeeded, want the access denied error")
+		}
+
+		entries := logs.entries(t, "carta: received response")
+		if len(entries) != 1 {
+			t.Fatalf("%s: logged %d responses, want 1", tc.header, len(entries))
+		}
+
+		if got := entries[0]["request_id"]; got != tc.want {
+			t.Errorf("%s: logged request id %v, want %q", tc.header, got, tc.want)
+		}
+
+		if tc.want == "" {
+			if strings.Contains(err.Error(), "carta request id") {
+				t.Errorf("error %q names a request id the response didn't carry", err)
+			}
+			continue
+		}
+
+		if !strings.Contains(err.Error(), "(carta request id: "+tc.want+")") {
+			t.Errorf("%s: error %q, want it to name the request id", tc.header, err)
+		}
+	}
+}
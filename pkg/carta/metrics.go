@@ -0,0 +1,31 @@
+package carta
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics receives request instrumentation from the Carta client, keyed by logical operation
+// (e.g. GetIssuers), so operators can wire it to their monitoring system.
+type Metrics interface {
+	// IncRequests counts every attempted request, including retries.
+	IncRequests(operation string)
+	// ObserveLatency records the duration of an attempted request.
+	ObserveLatency(operation string, duration time.Duration)
+	// IncRetries counts retries of a request.
+	IncRetries(operation string)
+	// IncErrors counts failed attempts, statusCode is zero when no response was received.
+	IncErrors(operation string, statusCode int)
+}
+
+// MetricsFlusher is implemented by metrics sinks that buffer data and need flushing when the client is closed.
+type MetricsFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) IncRequests(string)                   {}
+func (noopMetrics) ObserveLatency(string, time.Duration) {}
+func (noopMetrics) IncRetries(string)                    {}
+func (noopMetrics) IncErrors(string, int)                {}
@@ -0,0 +1,127 @@
+package carta
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMetrics records what the client reports, keyed by operation.
+type fakeMetrics struct {
+	mtx       sync.Mutex
+	requests  map[string]int
+	latencies map[string]int
+	retries   map[string]int
+	errors    map[string][]int
+	flushed   int
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{
+		requests:  map[string]int{},
+		latencies: map[string]int{},
+		retries:   map[string]int{},
+		errors:    map[string][]int{},
+	}
+}
+
+func (m *fakeMetrics) IncRequests(operation string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.requests[operation]++
+}
+
+func (m *fakeMetrics) ObserveLatency(operation string, _ time.Duration) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.latencies[operation]++
+}
+
+func (m *fakeMetrics) IncRetries(operation string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.retries[operation]++
+}
+
+func (m *fakeMetrics) IncErrors(operation string, statusCode int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.errors[operation] = append(m.errors[operation], statusCode)
+}
+
+func (m *fakeMetrics) Flush(context.Context) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.flushed++
+	return nil
+}
+
+func TestMetricsCountAttemptsRetriesAndErrors(t *testing.T) {
+	metrics := newFakeMetrics()
+	failures := 1
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/issuers" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		pageServer("").ServeHTTP(w, r)
+	}), WithRetry(1, time.Millisecond, time.Millisecond), WithMetrics(metrics))
+
+	if _, _, err := client.GetIssuers(context.Background(), PaginationParams{Size: 10}); err != nil {
+		t.Fatalf("GetIssuers() error = %v", err)
+	}
+
+	if got := metrics.requests["GetIssuers"]; got != 2 {
+		t.Errorf("counted %d GetIssuers requests, want 2", got)
+	}
+
+	if got := metrics.latencies["GetIssuers"]; got != 2 {
+		t.Errorf("observed %d GetIssuers latencies, want 2", got)
+	}
+
+	if got := metrics.retries["GetIssuers"]; got != 1 {
+		t.Errorf("counted %d GetIssuers retries, want 1", got)
+	}
+
+	if got := metrics.errors["GetIssuers"]; !reflect.DeepEqual(got, []int{http.StatusServiceUnavailable}) {
+		t.Errorf("counted GetIssuers errors %v, want [503]", got)
+	}
+}
+
+func TestMetricsCountTransportErrorsWithoutStatus(t *testing.T) {
+	metrics := newFakeMetrics()
+	client := NewClient(
+		testAccessToken,
+		&http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, context.DeadlineExceeded
+		})},
+		WithBaseURL("http://carta.invalid"),
+		WithRetry(0, time.Millisecond, time.Millisecond),
+		WithMetrics(metrics),
+	)
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); err == nil {
+		t.Fatal("GetIssuer() succeeded, want the transport error")
+	}
+
+	if got := metrics.errors["GetIssuer"]; !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("counted GetIssuer errors %v, want [0]", got)
+	}
+}
+
+func TestCloseFlushesMetrics(t *testing.T) {
+	metrics := newFakeMetrics()
+	client := newTestClient(t, pageServer(""), WithMetrics(metrics))
+
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if metrics.flushed != 1 {
+		t.Errorf("flushed metrics %d times, want 1", metrics.flushed)
+	}
+}
@@ -1,26 +1,148 @@
 package carta
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 type BaseResource struct {
 	Id string `json:"id"`
 }
 
 type Issuer struct {
 	BaseResource
-	Name    string `json:"legalName"`
-	Website string `json:"website"`
+	Name        string `json:"legalName"`
+	DisplayName string `json:"displayName"`
+	// AlternateNames are the trade (DBA) names the issuer is also known by.
+	AlternateNames    []string `json:"alternateNames"`
+	Website           string   `json:"website"`
+	Country           string   `json:"country"`
+	State             string   `json:"state"`
+	Type              string   `json:"issuerType"`
+	IncorporationDate string   `json:"incorporationDate"`
+	// StakeholderCount is the number of stakeholders on the issuer's cap table.
+	StakeholderCount int `json:"stakeholderCount"`
+	// ManagingFirmId is the id of the investor firm managing the issuer, if any.
+	ManagingFirmId string `json:"managingFirmId"`
+	// Roles are the roles the issuer plays besides issuing equity, e.g. fund_admin for fund administrators.
+	Roles []string `json:"roles"`
+	// ParentCompanyId is the id of the issuer owning this one, for subsidiaries.
+	ParentCompanyId string `json:"parentCompanyId"`
+	// Ticker and Exchange are only set for publicly traded issuers.
+	Ticker   string `json:"tickerSymbol"`
+	Exchange string `json:"exchange"`
+	// AccessLevel is the issuer's access within a portfolio, only set on portfolio issuer listings.
+	AccessLevel string `json:"accessLevel"`
+	// MemberType tells issuers apart from investor firms ("firm") among portfolio members,
+	// only set on portfolio issuer listings.
+	MemberType string `json:"memberType"`
+	// SystemManaged marks portfolio memberships maintained by Carta that can't be revoked,
+	// only set on portfolio issuer listings.
+	SystemManaged bool `json:"systemManaged"`
+	// Last409AValue and PostMoneyValuation are kept as the decimals Carta returns, so no precision is lost.
+	Last409AValue      json.Number `json:"last409aValue"`
+	PostMoneyValuation json.Number `json:"postMoneyValuation"`
+	// SecurityCount is the number of securities issued, nil when it wasn't fetched or is unavailable.
+	SecurityCount *int `json:"-"`
 }
 
 type Portfolio struct {
-	Id      string `json:"portfolioId"`
-	Name    string `json:"legalName"`
-	Issuers []Issuer
+	Id          string `json:"portfolioId"`
+	Name        string `json:"legalName"`
+	DisplayName string `json:"displayName"`
+	ParentId    string `json:"parentPortfolioId"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+	CreatedBy   string `json:"createdBy"`
+	// Firms are the investor firms that can access the portfolio, GetPortfolios doesn't fetch them.
+	Firms []InvestorFirm `json:"-"`
 }
 
 type InvestorFirm struct {
 	BaseResource
 	Name string `json:"name"`
+	// AssetsUnderManagement is kept as the decimal Carta returns, it may exceed what a float64 holds exactly.
+	AssetsUnderManagement json.Number `json:"assetsUnderManagement"`
+	FundCount             int         `json:"fundCount"`
+	FoundedYear           int         `json:"foundedYear"`
+}
+
+type InvestorMember struct {
+	BaseResource
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// InvestorContact is a named contact of an investor firm, who may be given access to portfolios.
+type InvestorContact struct {
+	BaseResource
+	Name         string   `json:"name"`
+	Email        string   `json:"email"`
+	PortfolioIds []string `json:"portfolioIds"`
+}
+
+// IssuerDocument is a document filed with an issuer, e.g. a board consent or a filing.
+type IssuerDocument struct {
+	BaseResource
+	Name string `json:"name"`
+	Type string `json:"documentType"`
+	Date string `json:"documentDate"`
+}
+
+// BoardMember is a member of the board of directors of an issuer.
+type BoardMember struct {
+	BaseResource
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Title string `json:"title"`
+}
+
+type IssuerContact struct {
+	BaseResource
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type ChangeType string
+
+const (
+	ChangeTypeCreated ChangeType = "created"
+	ChangeTypeUpdated ChangeType = "updated"
+	ChangeTypeDeleted ChangeType = "deleted"
+)
+
+// UnmarshalJSON parses change types case-insensitively.
+func (ct *ChangeType) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*ct = ChangeType(strings.ToLower(strings.TrimSpace(raw)))
+
+	return nil
+}
+
+type Change struct {
+	ResourceType string     `json:"resourceType"`
+	ResourceId   string     `json:"resourceId"`
+	ChangeType   ChangeType `json:"changeType"`
+	ChangedAt    time.Time  `json:"changedAt"`
 }
 
 type PaginationData struct {
-	Next string `json:"nextPageToken"`
+	Next  string `json:"nextPageToken"`
+	Total int    `json:"totalCount"`
+}
+
+func (p PaginationData) pagination() PaginationData {
+	return p
+}
+
+type Counts struct {
+	Issuers    int
+	Portfolios int
+	Investors  int
 }
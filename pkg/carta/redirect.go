@@ -0,0 +1,45 @@
+package carta
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// maxRedirects is the number of redirects followed per request, as net/http does by default.
+const maxRedirects = 10
+
+var errTooManyRedirects = errors.New("carta: stopped after too many redirects")
+
+// checkRedirect follows redirects, e.g. after a Carta host migration, but never sends the access token
+// to another host or over a downgraded connection.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+
+	previous := via[len(via)-1]
+	if sameOrigin(previous, req) {
+		return nil
+	}
+
+	req.Header.Del("Authorization")
+	c.logLevels.Logger(req.Context(), LogComponentClient).Warn(
+		"carta: request redirected to another host, following without the access token",
+		zap.String("from", previous.URL.Redacted()),
+		zap.String("to", req.URL.Redacted()),
+	)
+
+	return nil
+}
+
+// sameOrigin reports whether the redirect stays on the same host without downgrading from https.
+func sameOrigin(previous *http.Request, next *http.Request) bool {
+	if !strings.EqualFold(previous.URL.Host, next.URL.Host) {
+		return false
+	}
+
+	return previous.URL.Scheme != "https" || next.URL.Scheme == "https"
+}
@@ -0,0 +1,100 @@
+package carta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// redirectServer redirects every request to the same path on target.
+func redirectServer(target string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+}
+
+func TestRedirectToAnotherHostDropsAuthorization(t *testing.T) {
+	recorder := &requestRecorder{handler: echoIssuer}
+	target := httptest.NewServer(recorder)
+	t.Cleanup(target.Close)
+
+	// the same server under another host name
+	otherHost := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+
+	ctx, logs := newLogContext()
+	client := newTestClient(t, redirectServer(otherHost))
+
+	if _, err := client.GetIssuer(ctx, "acme"); err != nil {
+		t.Fatalf("GetIssuer() error = %v", err)
+	}
+
+	requests := recorder.requests()
+	if len(requests) != 1 {
+		t.Fatalf("redirect target received %d requests, want 1", len(requests))
+	}
+
+	if got := requests[0].header.Get("Authorization"); got != "" {
+		t.Errorf("redirect to another host sent authorization %q, want none", got)
+	}
+
+	if entries := logs.entries(t, "carta: request redirected to another host, following without the access token"); len(entries) != 1 {
+		t.Errorf("logged %d redirect warnings, want 1", len(entries))
+	}
+}
+
+func TestRedirectOnSameHostKeepsAuthorization(t *testing.T) {
+	recorder := &requestRecorder{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/issuers/acme" {
+			http.Redirect(w, r, "/v2/issuers/acme", http.StatusMovedPermanently)
+			return
+		}
+		echoIssuer.ServeHTTP(w, r)
+	})}
+	client := newTestClient(t, recorder)
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); err != nil {
+		t.Fatalf("GetIssuer() error = %v", err)
+	}
+
+	requests := recorder.requests()
+	if len(requests) != 2 || requests[1].escapedPath != "/v2/issuers/acme" {
+		t.Fatalf("received %+v, want the request and its redirect", requests)
+	}
+
+	if got := requests[1].header.Get("Authorization"); got != "Bearer "+testAccessToken {
+		t.Errorf("redirect on the same host sent authorization %q, want the access token", got)
+	}
+}
+
+func TestTooManyRedirects(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); !errors.Is(err, errTooManyRedirects) {
+		t.Errorf("GetIssuer() error = %v, want errTooManyRedirects", err)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	for _, tc := range []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{"https://api.carta.com/v1alpha1/issuers", "https://API.carta.com/v1/issuers", true},
+		{"http://api.carta.com/issuers", "https://api.carta.com/issuers", true},
+		{"https://api.carta.com/issuers", "http://api.carta.com/issuers", false},
+		{"https://api.carta.com/issuers", "https://api.eu.carta.com/issuers", false},
+		{"https://api.carta.com/issuers", "https://api.carta.com:8443/issuers", false},
+	} {
+		from := httptest.NewRequest(http.MethodGet, tc.from, nil)
+		to := httptest.NewRequest(http.MethodGet, tc.to, nil)
+		if got := sameOrigin(from, to); got != tc.want {
+			t.Errorf("sameOrigin(%s, %s) = %t, want %t", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
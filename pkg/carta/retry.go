@@ -0,0 +1,147 @@
+package carta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 10 * time.Second
+)
+
+// JitterStrategy controls how retry backoff delays are randomized.
+type JitterStrategy int
+
+const (
+	// FullJitter waits a random delay between zero and the exponential backoff.
+	FullJitter JitterStrategy = iota
+	// EqualJitter waits half the exponential backoff plus a random delay up to the other half.
+	EqualJitter
+	// NoJitter waits exactly the exponential backoff.
+	NoJitter
+)
+
+// retryableError marks a failed attempt that is worth retrying.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isTimeout reports whether the request failed because it timed out.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTruncatedBody reports whether reading the response body failed because the response was cut short.
+func isTruncatedBody(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isTruncatedJSON reports whether the body is the beginning of a JSON document that ends prematurely,
+// as opposed to a complete or otherwise malformed document.
+func isTruncatedJSON(data []byte) bool {
+	var raw json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(data)).Decode(&raw)
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	jitter     JitterStrategy
+	// retryTimeouts retries attempts that timed out, which is safe as long as requests are idempotent reads.
+	retryTimeouts bool
+
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+func newRetryPolicy() *retryPolicy {
+	return &retryPolicy{
+		maxRetries:    defaultMaxRetries,
+		baseDelay:     defaultRetryBaseDelay,
+		maxDelay:      defaultRetryMaxDelay,
+		jitter:        FullJitter,
+		retryTimeouts: true,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404 -- jitter doesn't need a secure source
+	}
+}
+
+// backoff returns the delay before the given retry attempt (starting at zero).
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.maxDelay
+	if attempt < 32 {
+		if exp := p.baseDelay << attempt; exp > 0 && exp < p.maxDelay {
+			delay = exp
+		}
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	switch p.jitter {
+	case NoJitter:
+		return delay
+	case EqualJitter:
+		half := delay / 2
+		return half + time.Duration(p.rand.Int63n(int64(delay-half)+1))
+	default:
+		return time.Duration(p.rand.Int63n(int64(delay) + 1))
+	}
+}
+
+// do runs the attempt until it succeeds, fails with a non retryable error or retries are exhausted.
+func (p *retryPolicy) do(ctx context.Context, attempt func() error) error {
+	for i := 0; ; i++ {
+		err := attempt()
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return err
+		}
+
+		if i >= p.maxRetries {
+			return retryable.err
+		}
+
+		timer := time.NewTimer(p.backoff(i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package carta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSeededRetryPolicy returns a retry policy drawing its jitter from a seeded source.
+func newSeededRetryPolicy(jitter JitterStrategy, seed int64) *retryPolicy {
+	p := newRetryPolicy()
+	p.baseDelay = 100 * time.Millisecond
+	p.maxDelay = time.Second
+	p.jitter = jitter
+	p.rand = rand.New(rand.NewSource(seed)) // #nosec G404 -- deterministic jitter for the test
+
+	return p
+}
+
+func TestBackoffJitterRanges(t *testing.T) {
+	// the exponential backoff doubles from the base delay and is capped at the max delay
+	exponential := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+		time.Second,
+	}
+
+	for _, tc := range []struct {
+		name   string
+		jitter JitterStrategy
+		min    func(time.Duration) time.Duration
+	}{
+		{"full jitter", FullJitter, func(time.Duration) time.Duration { return 0 }},
+		{"equal jitter", EqualJitter, func(d time.Duration) time.Duration { return d / 2 }},
+		{"no jitter", NoJitter, func(d time.Duration) time.Duration { return d }},
+	} {
+		p := newSeededRetryPolicy(tc.jitter, 42)
+
+		for attempt, want := range exponential {
+			for i := 0; i < 200; i++ {
+				delay := p.backoff(attempt)
+				if delay < tc.min(want) || delay > want {
+					t.Fatalf("%s: attempt %d delay = %v, want within [%v, %v]", tc.name, attempt, delay, tc.min(want), want)
+				}
+			}
+		}
+	}
+}
+
+func TestBackoffJitterIsSpread(t *testing.T) {
+	for _, jitter := range []JitterStrategy{FullJitter, EqualJitter} {
+		p := newSeededRetryPolicy(jitter, 7)
+
+		seen := make(map[time.Duration]struct{})
+		for i := 0; i < 50; i++ {
+			seen[p.backoff(3)] = struct{}{}
+		}
+
+		if len(seen) < 10 {
+			t.Errorf("jitter %d produced %d distinct delays out of 50, want them randomized", jitter, len(seen))
+		}
+	}
+}
+
+func TestBackoffJitterDeterministicForSeed(t *testing.T) {
+	first := newSeededRetryPolicy(FullJitter, 1)
+	second := newSeededRetryPolicy(FullJitter, 1)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if a, b := first.backoff(attempt), second.backoff(attempt); a != b {
+			t.Fatalf("attempt %d delays = %v and %v, want the same delays for the same seed", attempt, a, b)
+		}
+	}
+}
+
+func TestBackoffDefaultsToFullJitter(t *testing.T) {
+	if jitter := newRetryPolicy().jitter; jitter != FullJitter {
+		t.Errorf("default jitter = %d, want FullJitter", jitter)
+	}
+
+	client := NewClient("token", nil, WithJitterStrategy(EqualJitter))
+	if client.retry.jitter != EqualJitter {
+		t.Errorf("configured jitter = %d, want EqualJitter", client.retry.jitter)
+	}
+}
+
+func TestRetryOnTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		retryTimeouts bool
+		want          int32
+	}{
+		{retryTimeouts: true, want: 3},
+		{retryTimeouts: false, want: 1},
+	} {
+		var received int32
+		client := newTestClient(
+			t,
+			slowServer(time.Second, echoIssuer, &received),
+			WithRequestTimeout(10*time.Millisecond),
+			WithRetry(2, time.Millisecond, time.Millisecond),
+			WithRetryOnTimeout(tc.retryTimeouts),
+		)
+
+		if _, err := client.GetIssuer(context.Background(), "acme"); !isTimeout(err) {
+			t.Errorf("GetIssuer() retrying timeouts %t error = %v, want a timeout", tc.retryTimeouts, err)
+		}
+
+		if got := atomic.LoadInt32(&received); got != tc.want {
+			t.Errorf("GetIssuer() retrying timeouts %t sent %d requests, want %d", tc.retryTimeouts, got, tc.want)
+		}
+	}
+}
+
+// truncatingServer cuts the body of its first failures issuer responses short, either by closing the
+// connection before the declared content length or by ending a complete response mid-document.
+func truncatingServer(failures int32, declareLength bool, received *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limits" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if atomic.AddInt32(received, 1) > failures {
+			echoIssuer.ServeHTTP(w, r)
+			return
+		}
+
+		if declareLength {
+			w.Header().Set("Content-Length", "100")
+		}
+		_, _ = w.Write([]byte(`{"issuer": {"id": "ac`))
+	})
+}
+
+func TestTruncatedBodyRetried(t *testing.T) {
+	for _, declareLength := range []bool{true, false} {
+		var received int32
+		client := newTestClient(
+			t,
+			truncatingServer(1, declareLength, &received),
+			WithRetry(2, time.Millisecond, time.Millisecond),
+		)
+
+		issuer, err := client.GetIssuer(context.Background(), "acme")
+		if err != nil {
+			t.Fatalf("GetIssuer() with content length %t error = %v, want the retry to succeed", declareLength, err)
+		}
+
+		if issuer.Id != "acme" || atomic.LoadInt32(&received) != 2 {
+			t.Errorf("GetIssuer() with content length %t = %q after %d requests, want acme after 2", declareLength, issuer.Id, received)
+		}
+	}
+}
+
+func TestTruncatedBodyFailsOnceRetriesRunOut(t *testing.T) {
+	var received int32
+	client := newTestClient(t, truncatingServer(10, false, &received), WithRetry(1, time.Millisecond, time.Millisecond))
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("GetIssuer() error = %v, want the truncated body error", err)
+	}
+
+	if got := atomic.LoadInt32(&received); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+}
+
+func TestMalformedBodyNotRetried(t *testing.T) {
+	var received int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/limits" {
+			atomic.AddInt32(&received, 1)
+		}
+		_, _ = w.Write([]byte(`{"issuer": ]`))
+	}), WithRetry(2, time.Millisecond, time.Millisecond))
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); err == nil {
+		t.Fatal("GetIssuer() succeeded, want a decoding error")
+	}
+
+	if got := atomic.LoadInt32(&received); got != 1 {
+		t.Errorf("sent %d requests, want a malformed body not to be retried", got)
+	}
+}
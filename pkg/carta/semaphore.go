@@ -0,0 +1,41 @@
+package carta
+
+import "context"
+
+// requestSemaphore caps the number of concurrent in-flight requests to Carta.
+// A nil semaphore does not limit concurrency.
+type requestSemaphore struct {
+	slots chan struct{}
+}
+
+func newRequestSemaphore(maxInFlight int) *requestSemaphore {
+	if maxInFlight <= 0 {
+		return nil
+	}
+
+	return &requestSemaphore{
+		slots: make(chan struct{}, maxInFlight),
+	}
+}
+
+// acquire waits for a free slot, giving up when the context is done.
+func (s *requestSemaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *requestSemaphore) release() {
+	if s == nil {
+		return
+	}
+
+	<-s.slots
+}
@@ -0,0 +1,115 @@
+package carta
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightResult is the outcome of an upstream request shared by every caller of the same request.
+type flightResult struct {
+	data       []byte
+	statusCode int
+	attempts   int
+}
+
+// flight is an in-flight request whose result is shared by every caller of the same request.
+type flight struct {
+	done   chan struct{}
+	result flightResult
+	err    error
+	// waiters counts the callers still waiting for the result, the request is cancelled once none is left.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup coalesces identical concurrent requests into a single upstream request.
+type flightGroup struct {
+	mtx     sync.Mutex
+	flights map[string]*flight
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		flights: make(map[string]*flight),
+	}
+}
+
+// do runs fn for the first caller of key and makes concurrent callers of the same key wait for its
+// result, reporting whether the result was shared. fn runs under a context detached from the first
+// caller, so a caller that gives up doesn't fail the others; it is cancelled once every caller gave up.
+// Callers give up when their context is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (flightResult, error)) (flightResult, bool, error) {
+	g.mtx.Lock()
+	f, shared := g.flights[key]
+	if !shared {
+		flightCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+
+		go func() {
+			f.result, f.err = fn(flightCtx)
+
+			g.mtx.Lock()
+			g.forget(key, f)
+			g.mtx.Unlock()
+
+			cancel()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mtx.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result, shared, f.err
+	case <-ctx.Done():
+		g.mtx.Lock()
+		f.waiters--
+		last := f.waiters == 0
+		if last {
+			// nobody is left to use the result, later callers start a new request
+			g.forget(key, f)
+		}
+		g.mtx.Unlock()
+
+		// the last caller to give up cancels the request and waits for it to wind down, so nothing
+		// keeps running on behalf of callers that all returned
+		if last {
+			f.cancel()
+			<-f.done
+		}
+
+		return flightResult{}, shared, ctx.Err()
+	}
+}
+
+// forget drops the flight of key unless a newer flight replaced it, the caller holds the lock.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// detachedContext carries the values of a caller's context, e.g. its logger and span, without its
+// deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
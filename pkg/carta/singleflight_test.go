@@ -0,0 +1,265 @@
+package carta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupSharesConcurrentCalls(t *testing.T) {
+	g := newFlightGroup()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (flightResult, error) {
+		calls.Add(1)
+		<-release
+		return flightResult{data: []byte("data")}, nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	var sharedCount, entered atomic.Int32
+	caller := func() {
+		defer wg.Done()
+		entered.Add(1)
+		result, shared, err := g.do(context.Background(), "key", fn)
+		if err != nil || string(result.data) != "data" {
+			t.Errorf("do() = %q, %v, want the shared result", result.data, err)
+		}
+		if shared {
+			sharedCount.Add(1)
+		}
+	}
+
+	wg.Add(1)
+	go caller()
+	waitFor(t, func() bool { return calls.Load() == 1 })
+
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go caller()
+	}
+
+	// give the callers that entered time to find the flight before it lands
+	waitFor(t, func() bool { return entered.Load() == callers })
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("fn ran %d times, want 1", calls.Load())
+	}
+
+	if sharedCount.Load() != callers-1 {
+		t.Errorf("%d callers shared the result, want %d", sharedCount.Load(), callers-1)
+	}
+}
+
+func TestFlightGroupRunsSequentialCallsAgain(t *testing.T) {
+	g := newFlightGroup()
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if _, shared, err := g.do(context.Background(), "key", func(context.Context) (flightResult, error) {
+			calls++
+			return flightResult{}, errors.New("failed")
+		}); err == nil || shared {
+			t.Fatalf("do() = shared %v, %v, want the unshared error", shared, err)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("fn ran %d times, want a finished flight not to be reused", calls)
+	}
+}
+
+func TestFlightGroupWaiterGivesUp(t *testing.T) {
+	g := newFlightGroup()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	go func() {
+		_, _, _ = g.do(context.Background(), "key", func(context.Context) (flightResult, error) {
+			<-release
+			return flightResult{}, nil
+		})
+	}()
+	waitFor(t, func() bool { return waiting(g, "key") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := g.do(ctx, "key", func(context.Context) (flightResult, error) { return flightResult{}, nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("do() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFlightGroupFollowerOutlivesCancelledLeader(t *testing.T) {
+	g := newFlightGroup()
+
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (flightResult, error) {
+		<-release
+		if err := ctx.Err(); err != nil {
+			return flightResult{}, err
+		}
+		return flightResult{data: []byte("data")}, nil
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(leaderCtx, "key", fn)
+		leaderErr <- err
+	}()
+	waitFor(t, func() bool { return waiters(g, "key") == 1 })
+
+	type outcome struct {
+		result flightResult
+		shared bool
+		err    error
+	}
+	follower := make(chan outcome, 1)
+	go func() {
+		result, shared, err := g.do(context.Background(), "key", fn)
+		follower <- outcome{result, shared, err}
+	}()
+	waitFor(t, func() bool { return waiters(g, "key") == 2 })
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader do() error = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	got := <-follower
+	if got.err != nil || string(got.result.data) != "data" || !got.shared {
+		t.Fatalf("follower do() = %q, shared %v, %v, want the shared result", got.result.data, got.shared, got.err)
+	}
+}
+
+func TestFlightGroupCancelledOnceEveryCallerGaveUp(t *testing.T) {
+	g := newFlightGroup()
+
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, _, err := g.do(ctx, "key", func(flightCtx context.Context) (flightResult, error) {
+		close(started)
+		<-flightCtx.Done()
+		cancelled.Store(true)
+		return flightResult{}, flightCtx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("do() error = %v, want context.Canceled", err)
+	}
+
+	// the last caller to give up waits for the request to wind down
+	if !cancelled.Load() {
+		t.Error("the request kept running after its only caller gave up")
+	}
+
+	if waiting(g, "key") {
+		t.Error("the abandoned flight is still joinable")
+	}
+}
+
+// waiters returns the number of callers waiting for the flight of the key.
+func waiters(g *flightGroup, key string) int {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if f, ok := g.flights[key]; ok {
+		return f.waiters
+	}
+
+	return 0
+}
+
+// waiting reports whether a flight for the key is in progress.
+func waiting(g *flightGroup, key string) bool {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	_, ok := g.flights[key]
+	return ok
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClientSharedRequestOutlivesCancelledCaller(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		_, _ = w.Write([]byte(`{"issuer": {"id": "acme", "legalName": "Acme Corp"}}`))
+	}))
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.GetIssuer(leaderCtx, "acme")
+		leaderErr <- err
+	}()
+	waitFor(t, func() bool { return requests.Load() == 1 })
+
+	type outcome struct {
+		issuer Issuer
+		err    error
+	}
+	follower := make(chan outcome, 1)
+	go func() {
+		issuer, err := client.GetIssuer(context.Background(), "acme")
+		follower <- outcome{issuer, err}
+	}()
+	waitFor(t, func() bool { return waitingCallers(client.flights) == 2 })
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled GetIssuer() error = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	got := <-follower
+	if got.err != nil || got.issuer.Name != "Acme Corp" {
+		t.Fatalf("GetIssuer() = %+v, %v, want the issuer shared with the cancelled caller", got.issuer, got.err)
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("sent %d requests, want the callers to share one", requests.Load())
+	}
+}
+
+// waitingCallers returns the number of callers waiting across all flights.
+func waitingCallers(g *flightGroup) int {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	total := 0
+	for _, f := range g.flights {
+		total += f.waiters
+	}
+
+	return total
+}
@@ -0,0 +1,30 @@
+package carta
+
+import "context"
+
+// Tracer starts a span around every Carta request, so operators can wire the client to their
+// tracing system (e.g. an OpenTelemetry tracer).
+type Tracer interface {
+	// Start starts a span for the operation as a child of the span in ctx, if any, returning the
+	// context carrying the new span.
+	Start(ctx context.Context, operation string) (context.Context, Span)
+}
+
+// Span is a single traced Carta request.
+type Span interface {
+	// SetAttributes records attributes of the request, e.g. its method, URL and status code.
+	SetAttributes(attributes map[string]interface{})
+	// End finishes the span, err is the error the request failed with, if any.
+	End(err error)
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(map[string]interface{}) {}
+func (noopSpan) End(error)                            {}
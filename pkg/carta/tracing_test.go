@@ -0,0 +1,96 @@
+package carta
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordedSpan is a span kept in memory by spanRecorder.
+type recordedSpan struct {
+	operation  string
+	attributes map[string]interface{}
+	ended      bool
+	err        error
+}
+
+func (s *recordedSpan) SetAttributes(attributes map[string]interface{}) {
+	s.attributes = attributes
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+// spanRecorder is a tracer keeping the spans it started in memory.
+type spanRecorder struct {
+	mtx   sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *spanRecorder) Start(ctx context.Context, operation string) (context.Context, Span) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	span := &recordedSpan{operation: operation}
+	r.spans = append(r.spans, span)
+
+	return ctx, span
+}
+
+func TestTracerRecordsSpanPerRequest(t *testing.T) {
+	recorder := &spanRecorder{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issuers/acme" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"issuer": {"id": "acme"}}`))
+	}), WithTracer(recorder), WithExtraQueryParams(map[string]string{"secret": "s3cr3t"}))
+
+	if _, err := client.GetIssuer(context.Background(), "acme"); err != nil {
+		t.Fatalf("GetIssuer() error = %v", err)
+	}
+
+	if _, err := client.GetIssuer(context.Background(), "gone"); !IsNotFound(err) {
+		t.Fatalf("GetIssuer() error = %v, want not found", err)
+	}
+
+	if len(recorder.spans) != 2 {
+		t.Fatalf("recorded %d spans, want one per request", len(recorder.spans))
+	}
+
+	for i, want := range []struct {
+		url        string
+		statusCode int
+		failed     bool
+	}{
+		{"/issuers/acme", http.StatusOK, false},
+		{"/issuers/gone", http.StatusNotFound, true},
+	} {
+		span := recorder.spans[i]
+		if span.operation != "GetIssuer" || !span.ended {
+			t.Errorf("span %d = %s, ended %v, want an ended GetIssuer span", i, span.operation, span.ended)
+		}
+
+		if (span.err != nil) != want.failed {
+			t.Errorf("span %d error = %v, want failed %v", i, span.err, want.failed)
+		}
+
+		if code := span.attributes["http.status_code"]; code != want.statusCode {
+			t.Errorf("span %d status code = %v, want %d", i, code, want.statusCode)
+		}
+
+		if method := span.attributes["http.method"]; method != http.MethodGet {
+			t.Errorf("span %d method = %v, want GET", i, method)
+		}
+
+		url, _ := span.attributes["http.url"].(string)
+		if !strings.HasSuffix(url, want.url) || strings.Contains(url, "s3cr3t") {
+			t.Errorf("span %d url = %q, want %s without the query", i, url, want.url)
+		}
+	}
+}
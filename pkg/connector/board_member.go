@@ -0,0 +1,136 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type boardMemberResourceType struct {
+	resourceType *v2.ResourceType
+	client       *carta.Client
+	syncOptions  syncOptions
+}
+
+func (o *boardMemberResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for a board member of a Carta Issuer.
+func boardMemberResource(ctx context.Context, so syncOptions, member *carta.BoardMember, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"login":           member.Email,
+		"email":           member.Email,
+		"board_member_id": member.Id,
+	}
+
+	if member.Title != "" {
+		profile["board_member_title"] = member.Title
+	}
+
+	return newUserResource(
+		so.displayName(resourceTypeBoardMember, displayNameData{Name: member.Name, LegalName: member.Name, Id: member.Id}),
+		so.resourceId(member.Id),
+		resourceTypeBoardMember,
+		profile,
+		v2.UserTrait_Status_STATUS_UNSPECIFIED,
+		parentResourceID,
+	)
+}
+
+func (o *boardMemberResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// board members are only listed under their issuer
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeBoardMember.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	issuerId := o.syncOptions.cartaId(parentId.Resource)
+	members, nextToken, err := o.client.GetBoardMembersForIssuer(
+		ctx,
+		issuerId,
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeBoardMember.Id), After: bag.PageToken()},
+	)
+	if err != nil {
+		// a board hidden from the access token leaves the issuer without board seats, the rest of the sync goes on
+		if carta.IsAccessDenied(err) {
+			ctxzap.Extract(ctx).Warn(
+				"carta-connector: access token has no visibility of the issuer board, skipping its board members",
+				zap.String("issuer_id", issuerId),
+				zap.Error(err),
+			)
+			o.syncOptions.run.boardSeats().complete(issuerId)
+
+			return nil, "", nil, nil
+		}
+
+		return nil, "", nil, fmt.Errorf("carta-connector: failed to list issuer board members: %w", err)
+	}
+
+	// members sitting on several boards are listed under the first issuer they are found on, the board
+	// grants are created by each issuer
+	isRepeated := o.syncOptions.run.duplicateDetector(resourceTypeBoardMember.Id).repeats(parentId.Resource + "/" + bag.PageToken())
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	seats := make([]string, 0, len(members))
+	for _, member := range members {
+		if !isRepeated(member.Id) {
+			memberCopy := member
+			br, err := boardMemberResource(ctx, o.syncOptions, &memberCopy, parentId)
+
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			o.syncOptions.listedIds.add(resourceTypeBoardMember.Id, member.Id)
+			rv = append(rv, br)
+		}
+
+		// the seat points at the member resource as it was first listed, whichever board listed it
+		seats = append(seats, o.syncOptions.listedIds.resolve(resourceTypeBoardMember.Id, member.Id))
+	}
+
+	o.syncOptions.run.boardSeats().add(issuerId, seats)
+	if nextToken == "" {
+		o.syncOptions.run.boardSeats().complete(issuerId)
+	}
+
+	if err := o.syncOptions.countResources(resourceTypeBoardMember.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+func (o *boardMemberResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns no grants, board grants are created by the issuers, as members may sit on several boards
+// while their resource has a single parent.
+func (o *boardMemberResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func boardMemberBuilder(client *carta.Client, syncOptions syncOptions) *boardMemberResourceType {
+	return &boardMemberResourceType{
+		resourceType: resourceTypeBoardMember,
+		client:       client,
+		syncOptions:  syncOptions,
+	}
+}
@@ -0,0 +1,58 @@
+package connector
+
+import (
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+)
+
+// boardSeats records the board members listed under each issuer during a sync, so the issuer grants its
+// board seats without fetching its board again. Members sitting on several boards are recorded on each.
+type boardSeats struct {
+	mtx sync.Mutex
+	// members maps each issuer id, normalized, to the ids of its board members as they were synced.
+	members map[string][]string
+	// listed records the issuers, normalized, whose board was listed to the last page.
+	listed map[string]struct{}
+}
+
+func newBoardSeats() *boardSeats {
+	return &boardSeats{
+		members: make(map[string][]string),
+		listed:  make(map[string]struct{}),
+	}
+}
+
+// add records a page of the issuer's board members.
+func (b *boardSeats) add(issuerId string, memberIds []string) {
+	issuerId = carta.NormalizeId(issuerId)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.members[issuerId] = append(b.members[issuerId], memberIds...)
+}
+
+// complete records that the issuer's board was listed to the last page.
+func (b *boardSeats) complete(issuerId string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.listed[carta.NormalizeId(issuerId)] = struct{}{}
+}
+
+// seats returns the board members of the issuer, it returns false when the issuer's board wasn't listed
+// to the last page during the sync.
+func (b *boardSeats) seats(issuerId string) ([]string, bool) {
+	issuerId = carta.NormalizeId(issuerId)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if _, ok := b.listed[issuerId]; !ok {
+		return nil, false
+	}
+
+	// a page listed again when the sync retries it records its members twice
+	return uniqueIds(b.members[issuerId]), true
+}
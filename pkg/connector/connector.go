@@ -2,6 +2,11 @@ package connector
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/ConductorOne/baton-carta/pkg/carta"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -9,6 +14,7 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 var (
@@ -19,6 +25,13 @@ var (
 			v2.ResourceType_TRAIT_USER,
 		},
 	}
+	resourceTypeFund = &v2.ResourceType{
+		Id:          "fund",
+		DisplayName: "Fund",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_USER,
+		},
+	}
 	resourceTypePortfolio = &v2.ResourceType{
 		Id:          "portfolio",
 		DisplayName: "Portfolio",
@@ -29,21 +42,372 @@ var (
 	resourceTypeInvestor = &v2.ResourceType{
 		Id:          "investor",
 		DisplayName: "Investor",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeIssuerContact = &v2.ResourceType{
+		Id:          "issuer_contact",
+		DisplayName: "Issuer Contact",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_USER,
+		},
+	}
+	resourceTypeInvestorMember = &v2.ResourceType{
+		Id:          "investor_member",
+		DisplayName: "Investor Member",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_USER,
+		},
+	}
+	resourceTypeIssuerDocument = &v2.ResourceType{
+		Id:          "issuer_document",
+		DisplayName: "Issuer Document",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeBoardMember = &v2.ResourceType{
+		Id:          "board_member",
+		DisplayName: "Board Member",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_USER,
+		},
+	}
+	resourceTypeInvestorContact = &v2.ResourceType{
+		Id:          "investor_contact",
+		DisplayName: "Investor Contact",
 		Traits: []v2.ResourceType_Trait{
 			v2.ResourceType_TRAIT_USER,
 		},
 	}
 )
 
+// syncOptions holds the connector settings shared by resource syncers.
+type syncOptions struct {
+	updatedSince        time.Time
+	continueOnPageError bool
+	lazyIssuers         bool
+	splitFunds          bool
+	// stakeholderWarningThreshold flags issuers with more stakeholders than this, zero disables the warning.
+	stakeholderWarningThreshold int
+	adaptivePageSize            bool
+	// pageSizes overrides ResourcesPageSize for listings of specific resource types, keyed by resource type id.
+	pageSizes map[string]int
+	// startTokens are Carta page tokens, keyed by resource type id, that listings resume from.
+	startTokens map[string]string
+	// idPrefix is prepended to every resource id, so multiple connector instances can ingest the same Carta data.
+	idPrefix string
+	// portfolioNamePrefix limits synced portfolios to those named with the prefix.
+	portfolioNamePrefix string
+	// skipEmptyPortfolios leaves out portfolios without issuers nor firms, as they produce no grants.
+	skipEmptyPortfolios bool
+	// securityCounts enriches issuers with the number of their securities, at the cost of a request per issuer.
+	securityCounts bool
+	// resourceCap limits the resources emitted across all resource types, nil means no limit.
+	resourceCap *resourceCap
+	// logLevels sets the log level of specific log components.
+	logLevels carta.LogLevels
+	// displayNameTemplate formats resource display names, nil keeps the Carta names.
+	displayNameTemplate *template.Template
+	// listedIds resolves ids returned by other endpoints to the ids issuers, portfolios and investors were listed with.
+	listedIds *idIndex
+	// run holds the state built up while listing resources, it's dropped when a new sync starts.
+	run *syncRun
+}
+
 type Carta struct {
-	client *carta.Client
+	client             *carta.Client
+	syncOptions        syncOptions
+	baseURL            string
+	insecureSkipVerify bool
+	debugHeaders       []string
+	extraQueryParams   map[string]string
+	acceptHeader       string
+	maxInFlight        int
+	detectDrift        bool
+	requestTimeout     time.Duration
+	operationTimeouts  map[string]time.Duration
+	maxIssuerPages     int
+	tracer             carta.Tracer
+	noTimeoutRetries   bool
+	maxPageSize        int
+	circuitBreaker     *circuitBreakerConfig
+	// displayNameTemplate is parsed by New, so an invalid template can be reported and ignored.
+	displayNameTemplate string
+}
+
+// Option configures optional behaviour of the Carta connector.
+type Option func(*Carta)
+
+// WithUpdatedSince makes issuer and investor syncs fetch only resources changed after the given time.
+func WithUpdatedSince(updatedSince time.Time) Option {
+	return func(c *Carta) {
+		c.syncOptions.updatedSince = updatedSince
+	}
+}
+
+// WithContinueOnPageError makes a failed page end the listing of its resource type with a warning,
+// keeping previously synced pages, instead of failing the whole sync.
+func WithContinueOnPageError(continueOnPageError bool) Option {
+	return func(c *Carta) {
+		c.syncOptions.continueOnPageError = continueOnPageError
+	}
+}
+
+// WithBaseURL connects to another Carta API base URL than carta.BaseURL, e.g. a staging or mock API.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Carta) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification.
+// This is strictly meant for testing against local or staging mocks with self-signed certificates.
+func WithInsecureSkipVerify(insecureSkipVerify bool) Option {
+	return func(c *Carta) {
+		c.insecureSkipVerify = insecureSkipVerify
+	}
+}
+
+// WithDebugHeaders logs the allow-listed outgoing request headers, authorization is always excluded.
+func WithDebugHeaders(headers []string) Option {
+	return func(c *Carta) {
+		c.debugHeaders = headers
+	}
+}
+
+// WithExtraQueryParams passes Carta specific query parameters (e.g. feature flags) on every request.
+func WithExtraQueryParams(params map[string]string) Option {
+	return func(c *Carta) {
+		c.extraQueryParams = params
+	}
+}
+
+// WithLazyIssuers skips the full issuer listing and only lists the issuers that are members of portfolios,
+// for tenants where only portfolio membership matters.
+func WithLazyIssuers(lazyIssuers bool) Option {
+	return func(c *Carta) {
+		c.syncOptions.lazyIssuers = lazyIssuers
+	}
+}
+
+// WithSplitFunds syncs fund issuers under a separate fund resource type, so policies can treat them separately.
+func WithSplitFunds(splitFunds bool) Option {
+	return func(c *Carta) {
+		c.syncOptions.splitFunds = splitFunds
+	}
+}
+
+// WithStakeholderWarningThreshold warns about issuers whose stakeholder count exceeds the threshold, as
+// syncing their stakeholders would be expensive. The warning is logged and annotated on the issuer page.
+func WithStakeholderWarningThreshold(threshold int) Option {
+	return func(c *Carta) {
+		c.syncOptions.stakeholderWarningThreshold = threshold
+	}
+}
+
+// WithAcceptHeader overrides the accept header sent to Carta, e.g. application/vnd.carta.v1+json.
+func WithAcceptHeader(accept string) Option {
+	return func(c *Carta) {
+		c.acceptHeader = accept
+	}
+}
+
+// WithAdaptivePageSize tunes the page size of issuer, portfolio and investor listings
+// based on the observed page latency and errors.
+func WithAdaptivePageSize(adaptivePageSize bool) Option {
+	return func(c *Carta) {
+		c.syncOptions.adaptivePageSize = adaptivePageSize
+	}
+}
+
+// WithResourcePageSizes sets the page size of listings per resource type, keyed by resource type id,
+// resource types without a page size use ResourcesPageSize.
+func WithResourcePageSizes(pageSizes map[string]int) Option {
+	return func(c *Carta) {
+		c.syncOptions.pageSizes = pageSizes
+	}
+}
+
+// WithStartTokens resumes issuer, portfolio and investor listings from persisted Carta page tokens,
+// keyed by resource type id, instead of starting from scratch.
+func WithStartTokens(startTokens map[string]string) Option {
+	return func(c *Carta) {
+		c.syncOptions.startTokens = startTokens
+	}
+}
+
+// WithMaxConcurrentRequests caps the number of requests in flight to Carta at the same time.
+func WithMaxConcurrentRequests(maxInFlight int) Option {
+	return func(c *Carta) {
+		c.maxInFlight = maxInFlight
+	}
+}
+
+// WithPaginationDriftDetection warns when a listing's total count changes between its pages during a sync.
+func WithPaginationDriftDetection(detectDrift bool) Option {
+	return func(c *Carta) {
+		c.detectDrift = detectDrift
+	}
+}
+
+// WithResourceIdPrefix prepends the prefix to the id of every synced resource.
+// Carta ids used in requests are derived from resource ids by stripping the prefix again.
+func WithResourceIdPrefix(prefix string) Option {
+	return func(c *Carta) {
+		c.syncOptions.idPrefix = prefix
+	}
+}
+
+// WithRequestTimeout bounds every request to Carta to the given duration.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *Carta) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithOperationTimeouts overrides the request timeout of specific client operations, e.g. the slow
+// per-portfolio issuer walk of GetIssuersForPortfolio.
+func WithOperationTimeouts(timeouts map[string]time.Duration) Option {
+	return func(c *Carta) {
+		c.operationTimeouts = timeouts
+	}
+}
+
+// WithMaxPortfolioIssuerPages caps the issuer pages fetched per portfolio to bound the sync time,
+// portfolios past the cap are synced with the issuers gathered so far.
+func WithMaxPortfolioIssuerPages(maxPages int) Option {
+	return func(c *Carta) {
+		c.maxIssuerPages = maxPages
+	}
+}
+
+// WithTracer traces every request to Carta with the given tracer, requests aren't traced by default.
+func WithTracer(tracer carta.Tracer) Option {
+	return func(c *Carta) {
+		c.tracer = tracer
+	}
+}
+
+// circuitBreakerConfig is the circuit breaker configured with WithCircuitBreaker.
+type circuitBreakerConfig struct {
+	threshold int
+	cooldown  time.Duration
+}
+
+// WithCircuitBreaker stops sending requests to Carta for the cooldown window after the given number
+// of consecutive failures, a threshold of zero disables the breaker. The client opens the circuit
+// after carta.DefaultCircuitBreakerThreshold failures for carta.DefaultCircuitBreakerCooldown by default.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Carta) {
+		c.circuitBreaker = &circuitBreakerConfig{threshold: threshold, cooldown: cooldown}
+	}
+}
+
+// WithRetryOnTimeout controls whether requests to Carta that timed out are retried, they are by default.
+func WithRetryOnTimeout(retryTimeouts bool) Option {
+	return func(c *Carta) {
+		c.noTimeoutRetries = !retryTimeouts
+	}
+}
+
+// WithPortfolioNamePrefix syncs only portfolios whose name starts with the prefix, e.g. a naming convention.
+func WithPortfolioNamePrefix(prefix string) Option {
+	return func(c *Carta) {
+		c.syncOptions.portfolioNamePrefix = prefix
+	}
+}
+
+// WithSecurityCounts adds the number of securities of each issuer to its profile, read from the total
+// count of a single item page. Issuers keep no security count when the endpoint is unavailable.
+func WithSecurityCounts(securityCounts bool) Option {
+	return func(c *Carta) {
+		c.syncOptions.securityCounts = securityCounts
+	}
+}
+
+// WithMaxResources stops the sync with ErrResourceCapReached once more than maxResources resources
+// were listed across all resource types, as a safety valve against runaway syncs.
+func WithMaxResources(maxResources int) Option {
+	return func(c *Carta) {
+		if maxResources <= 0 {
+			c.syncOptions.resourceCap = nil
+			return
+		}
+
+		c.syncOptions.resourceCap = &resourceCap{max: maxResources}
+	}
+}
+
+// WithLogLevels sets the log level of specific log components, e.g. grants=debug, both
+// for the connector and the Carta client.
+func WithLogLevels(levels carta.LogLevels) Option {
+	return func(c *Carta) {
+		c.syncOptions.logLevels = levels
+	}
+}
+
+// WithDisplayNameTemplate formats resource display names with a text/template, e.g. "{{.Name}} ({{.Type}})",
+// see displayNameData for the available fields. Resources the template fails for keep their legal name.
+func WithDisplayNameTemplate(displayNameTemplate string) Option {
+	return func(c *Carta) {
+		c.displayNameTemplate = displayNameTemplate
+	}
+}
+
+// WithSkipEmptyPortfolios leaves out portfolios without issuers nor investor firms, which produce no grants.
+// Empty portfolios are synced by default.
+func WithSkipEmptyPortfolios(skipEmptyPortfolios bool) Option {
+	return func(c *Carta) {
+		c.syncOptions.skipEmptyPortfolios = skipEmptyPortfolios
+	}
+}
+
+// WithMaxPageSize caps the page size of requests when Carta doesn't report its own page size limit.
+func WithMaxPageSize(maxPageSize int) Option {
+	return func(c *Carta) {
+		c.maxPageSize = maxPageSize
+	}
 }
 
 func (c *Carta) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		issuerBuilder(c.client),
-		portfolioBuilder(c.client),
-		investorBuilder(c.client),
+	syncers := []connectorbuilder.ResourceSyncer{
+		issuerBuilder(c.client, c.syncOptions),
+		issuerContactBuilder(c.client, c.syncOptions),
+		issuerDocumentBuilder(c.client, c.syncOptions),
+		boardMemberBuilder(c.client, c.syncOptions),
+		portfolioBuilder(c.client, c.syncOptions),
+		investorBuilder(c.client, c.syncOptions),
+		investorMemberBuilder(c.client, c.syncOptions),
+		investorContactBuilder(c.client, c.syncOptions),
+	}
+
+	if c.syncOptions.splitFunds {
+		syncers = append(syncers, fundBuilder(c.client, c.syncOptions))
+	}
+
+	return syncers
+}
+
+// registerRelists registers the replays of the top level listings the grants build on. The resources
+// listed again were counted by the sync that listed them first, so they don't count towards the cap.
+func (c *Carta) registerRelists() {
+	relistOptions := c.syncOptions
+	relistOptions.resourceCap = nil
+
+	syncers := map[string]connectorbuilder.ResourceSyncer{
+		resourceTypeIssuer.Id:    issuerBuilder(c.client, relistOptions),
+		resourceTypePortfolio.Id: portfolioBuilder(c.client, relistOptions),
+		resourceTypeInvestor.Id:  investorBuilder(c.client, relistOptions),
+	}
+
+	if c.syncOptions.splitFunds {
+		syncers[resourceTypeFund.Id] = fundBuilder(c.client, relistOptions)
+	}
+
+	for resourceTypeID, syncer := range syncers {
+		c.syncOptions.run.onRelist(resourceTypeID, relister(resourceTypeID, syncer))
 	}
 }
 
@@ -54,18 +418,140 @@ func (c *Carta) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 }
 
 func (c *Carta) Validate(ctx context.Context) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	// log expected volumes, this is informational only and never fails validation
+	counts, err := c.client.Counts(ctx)
+	if err != nil {
+		l.Debug("carta-connector: unable to fetch resource counts", zap.Error(err))
+		return nil, nil
+	}
+
+	l.Info(
+		"carta-connector: expected resource counts",
+		zap.Int("issuers", counts.Issuers),
+		zap.Int("portfolios", counts.Portfolios),
+		zap.Int("investors", counts.Investors),
+	)
+
 	return nil, nil
 }
 
+// Close releases the resources held by the connector at the end of a sync.
+func (c *Carta) Close(ctx context.Context) error {
+	return c.client.Close(ctx)
+}
+
 // New returns the Carta connector.
-func New(ctx context.Context, accessToken string) (*Carta, error) {
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+func New(ctx context.Context, accessToken string, opts ...Option) (*Carta, error) {
+	l := ctxzap.Extract(ctx)
+
+	if strings.TrimSpace(accessToken) == "" {
+		return nil, fmt.Errorf("carta-connector: access token must not be empty")
+	}
+
+	cartaConnector := &Carta{}
+	for _, opt := range opts {
+		opt(cartaConnector)
+	}
+
+	cartaConnector.syncOptions.listedIds = newIdIndex()
+	cartaConnector.syncOptions.run = newSyncRun()
+	cartaConnector.syncOptions.run.onReset(cartaConnector.syncOptions.listedIds.reset)
+	if cartaConnector.syncOptions.resourceCap != nil {
+		cartaConnector.syncOptions.run.onReset(cartaConnector.syncOptions.resourceCap.reset)
+	}
+
+	if cartaConnector.baseURL != "" {
+		if err := validateBaseURL(cartaConnector.baseURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if cartaConnector.displayNameTemplate != "" {
+		displayNameTemplate, err := parseDisplayNameTemplate(cartaConnector.displayNameTemplate)
+		if err != nil {
+			l.Warn("carta-connector: invalid display name template, keeping the Carta names", zap.Error(err))
+		} else {
+			cartaConnector.syncOptions.displayNameTemplate = displayNameTemplate
+		}
+	}
+
+	httpOptions := []uhttp.Option{uhttp.WithLogger(true, l)}
+	if cartaConnector.insecureSkipVerify {
+		l.Warn("TLS certificate verification is DISABLED, never use insecure-skip-verify against production Carta")
+		httpOptions = append(httpOptions, uhttp.WithTLSClientConfig(&tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true, // #nosec G402 -- explicitly opted into for local/staging mocks only
+		}))
+	}
+
+	httpClient, err := uhttp.NewClient(ctx, httpOptions...)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &Carta{
-		client: carta.NewClient(accessToken, httpClient),
-	}, nil
+	var clientOptions []carta.ClientOption
+	if cartaConnector.baseURL != "" {
+		clientOptions = append(clientOptions, carta.WithBaseURL(cartaConnector.baseURL))
+	}
+
+	if len(cartaConnector.syncOptions.logLevels) > 0 {
+		clientOptions = append(clientOptions, carta.WithLogLevels(cartaConnector.syncOptions.logLevels))
+	}
+
+	if len(cartaConnector.debugHeaders) > 0 {
+		clientOptions = append(clientOptions, carta.WithDebugHeaders(cartaConnector.debugHeaders...))
+	}
+
+	if len(cartaConnector.extraQueryParams) > 0 {
+		clientOptions = append(clientOptions, carta.WithExtraQueryParams(cartaConnector.extraQueryParams))
+	}
+
+	if cartaConnector.acceptHeader != "" {
+		clientOptions = append(clientOptions, carta.WithAcceptHeader(cartaConnector.acceptHeader))
+	}
+
+	if cartaConnector.maxInFlight > 0 {
+		clientOptions = append(clientOptions, carta.WithMaxConcurrentRequests(cartaConnector.maxInFlight))
+	}
+
+	if cartaConnector.detectDrift {
+		clientOptions = append(clientOptions, carta.WithPaginationDriftDetection(true))
+	}
+
+	if cartaConnector.requestTimeout > 0 {
+		clientOptions = append(clientOptions, carta.WithRequestTimeout(cartaConnector.requestTimeout))
+	}
+
+	if len(cartaConnector.operationTimeouts) > 0 {
+		clientOptions = append(clientOptions, carta.WithOperationTimeouts(cartaConnector.operationTimeouts))
+	}
+
+	if cartaConnector.maxIssuerPages > 0 {
+		clientOptions = append(clientOptions, carta.WithMaxPortfolioIssuerPages(cartaConnector.maxIssuerPages))
+	}
+
+	if cartaConnector.tracer != nil {
+		clientOptions = append(clientOptions, carta.WithTracer(cartaConnector.tracer))
+	}
+
+	if cartaConnector.noTimeoutRetries {
+		clientOptions = append(clientOptions, carta.WithRetryOnTimeout(false))
+	}
+
+	if cartaConnector.maxPageSize > 0 {
+		clientOptions = append(clientOptions, carta.WithMaxPageSize(cartaConnector.maxPageSize))
+	}
+
+	if cartaConnector.circuitBreaker != nil {
+		clientOptions = append(clientOptions, carta.WithCircuitBreaker(cartaConnector.circuitBreaker.threshold, cartaConnector.circuitBreaker.cooldown))
+	}
+
+	cartaConnector.client = carta.NewClient(accessToken, httpClient, clientOptions...)
+	cartaConnector.syncOptions.run.onReset(cartaConnector.client.ClearCache)
+	cartaConnector.registerRelists()
+
+	return cartaConnector, nil
 }
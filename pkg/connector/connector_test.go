@@ -0,0 +1,81 @@
+package connector
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewRejectsEmptyAccessToken(t *testing.T) {
+	for _, accessToken := range []string{"", "   "} {
+		_, err := New(context.Background(), accessToken)
+		if err == nil || !strings.Contains(err.Error(), "access token") {
+			t.Errorf("New(%q) error = %v, want an access token error", accessToken, err)
+		}
+	}
+}
+
+func TestNewRejectsMalformedBaseURL(t *testing.T) {
+	for _, baseURL := range []string{
+		"mock-api.carta.com/v1alpha1/",
+		"/v1alpha1/",
+		"ftp://mock-api.carta.com/v1alpha1/",
+		"https://",
+		"https://mock-api.carta.com/%zz",
+	} {
+		_, err := New(context.Background(), "token", WithBaseURL(baseURL))
+		if err == nil || !strings.Contains(err.Error(), "base URL") {
+			t.Errorf("New with base URL %q error = %v, want a base URL error", baseURL, err)
+		}
+	}
+}
+
+func TestNewAcceptsBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "https://mock-api.carta.com/v1alpha1/", "http://localhost:8080/v1alpha1"} {
+		if _, err := New(context.Background(), "token", WithBaseURL(baseURL)); err != nil {
+			t.Errorf("New with base URL %q error = %v", baseURL, err)
+		}
+	}
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	f := newFixtureCarta(t)
+	server := httptest.NewUnstartedServer(f)
+	// the handshakes the client rejects aren't logged
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// the test server's certificate is self-signed, so only a client skipping verification reaches it
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "default", wantErr: true},
+		{name: "disabled", opts: []Option{WithInsecureSkipVerify(false)}, wantErr: true},
+		{name: "enabled", opts: []Option{WithInsecureSkipVerify(true)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cartaConnector, err := New(testContext(t), "token", append([]Option{WithBaseURL(server.URL + fakeBasePath)}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("failed to create connector: %v", err)
+			}
+
+			_, err = cartaConnector.client.GetIssuer(testContext(t), "acme")
+			if tc.wantErr {
+				var certErr *tls.CertificateVerificationError
+				if !errors.As(err, &certErr) {
+					t.Errorf("GetIssuer() error = %v, want a certificate verification error", err)
+				}
+			} else if err != nil {
+				t.Errorf("GetIssuer() error = %v", err)
+			}
+		})
+	}
+}
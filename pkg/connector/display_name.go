@@ -0,0 +1,53 @@
+package connector
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// displayNameData is what a display name template can refer to, e.g. "{{.Name}} ({{.Type}})".
+type displayNameData struct {
+	// Name is the display name used without a template.
+	Name string
+	// LegalName is the legal name of the resource, the fallback when the template can't be applied.
+	LegalName string
+	// Id is the Carta id of the resource.
+	Id string
+	// Type is the resource type id, e.g. issuer.
+	Type string
+	// Country and State are the jurisdiction of issuers, empty for other resource types.
+	Country string
+	State   string
+}
+
+// parseDisplayNameTemplate parses a display name template, references to unknown fields only fail
+// when it's applied.
+func parseDisplayNameTemplate(raw string) (*template.Template, error) {
+	return template.New("display-name").Parse(raw)
+}
+
+// displayName applies the configured display name template, falling back to the legal name when
+// the template fails or renders a blank name.
+func (so syncOptions) displayName(resourceType *v2.ResourceType, data displayNameData) string {
+	if so.displayNameTemplate == nil {
+		return data.Name
+	}
+
+	data.Type = resourceType.Id
+
+	var rendered bytes.Buffer
+	if err := so.displayNameTemplate.Execute(&rendered, data); err == nil {
+		if name := strings.TrimSpace(rendered.String()); name != "" {
+			return name
+		}
+	}
+
+	if data.LegalName != "" {
+		return data.LegalName
+	}
+
+	return data.Name
+}
@@ -0,0 +1,67 @@
+package connector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// duplicateDetector remembers the Carta ids listed during a sync, so an id Carta returns twice is
+// synced once instead of as conflicting resources.
+type duplicateDetector struct {
+	mtx sync.Mutex
+	// pages maps each listed id, normalized, to the page token of the page it was first listed on.
+	pages map[string]string
+}
+
+func newDuplicateDetector() *duplicateDetector {
+	return &duplicateDetector{
+		pages: make(map[string]string),
+	}
+}
+
+// page returns a check of the ids listed on the page with the given token, warning about the duplicates.
+// Ids first listed on that very page aren't duplicates, as the same page may be listed again when the sync
+// retries it.
+func (d *duplicateDetector) page(pageToken string) func(ctx context.Context, resourceTypeID string, id string) bool {
+	isRepeated := d.repeats(pageToken)
+
+	return func(ctx context.Context, resourceTypeID string, id string) bool {
+		if !isRepeated(id) {
+			return false
+		}
+
+		ctxzap.Extract(ctx).Warn(
+			"carta-connector: duplicate resource id returned by Carta, keeping the first occurrence",
+			zap.String("resource_type", resourceTypeID),
+			zap.String("id", id),
+		)
+
+		return true
+	}
+}
+
+// repeats returns a check of the ids listed on the page with the given token, reporting the ids listed
+// before on another page or earlier on the same page.
+func (d *duplicateDetector) repeats(pageToken string) func(id string) bool {
+	onPage := make(map[string]struct{})
+
+	return func(id string) bool {
+		key := carta.NormalizeId(id)
+
+		d.mtx.Lock()
+		firstPage, listed := d.pages[key]
+		if !listed {
+			d.pages[key] = pageToken
+		}
+		d.mtx.Unlock()
+
+		_, repeated := onPage[key]
+		onPage[key] = struct{}{}
+
+		return repeated || (listed && firstPage != pageToken)
+	}
+}
@@ -0,0 +1,345 @@
+package connector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+)
+
+// fakeBasePath is the path the fake Carta API is served under.
+const fakeBasePath = "/v1alpha1/"
+
+// fakePortfolio is a portfolio served by the fake Carta API along with its members and the firms it is shared with.
+type fakePortfolio struct {
+	portfolio carta.Portfolio
+	members   []carta.Issuer
+	firms     []carta.InvestorFirm
+}
+
+// fakeCarta is an in-memory Carta API serving the endpoints the connector uses. Listings are paged
+// by pageLimit items, so every sync walks several pages.
+type fakeCarta struct {
+	mtx       sync.Mutex
+	server    *httptest.Server
+	pageLimit int
+
+	issuers []carta.Issuer
+	// hiddenIssuers are issuers that can be fetched by id without being part of the issuer listing.
+	hiddenIssuers []carta.Issuer
+	contacts      map[string]carta.IssuerContact
+	documents     map[string][]carta.IssuerDocument
+	boardMembers  map[string][]carta.BoardMember
+	portfolios    []fakePortfolio
+	firms         []carta.InvestorFirm
+	firmMembers   map[string][]carta.InvestorMember
+	firmIssuers   map[string][]carta.Issuer
+	firmContacts  map[string][]carta.InvestorContact
+
+	// failures answers requests to the given paths, relative to the base path, with the status code.
+	failures map[string]int
+	// requests counts the requests made to each path, relative to the base path.
+	requests map[string]int
+
+	// latency delays every response, so requests made at the same time are in flight together.
+	latency time.Duration
+	// inFlight counts the requests being served, maxInFlight the most served at the same time.
+	inFlight    int32
+	maxInFlight int32
+}
+
+// newFakeCarta serves an empty fake Carta API for the duration of the test.
+func newFakeCarta(t *testing.T) *fakeCarta {
+	t.Helper()
+
+	f := &fakeCarta{
+		pageLimit:    2,
+		contacts:     make(map[string]carta.IssuerContact),
+		documents:    make(map[string][]carta.IssuerDocument),
+		boardMembers: make(map[string][]carta.BoardMember),
+		firmMembers:  make(map[string][]carta.InvestorMember),
+		firmIssuers:  make(map[string][]carta.Issuer),
+		firmContacts: make(map[string][]carta.InvestorContact),
+		failures:     make(map[string]int),
+		requests:     make(map[string]int),
+	}
+
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+// newFixtureCarta serves a fake Carta API holding a small tenant: issuers with contacts, documents and
+// board members, nested portfolios shared with an investor firm, and the firm's members and contacts.
+func newFixtureCarta(t *testing.T) *fakeCarta {
+	t.Helper()
+
+	f := newFakeCarta(t)
+
+	acme := fakeIssuer("acme", "Acme Corp")
+	globex := fakeIssuer("globex", "Globex")
+	initech := fakeIssuer("initech", "Initech")
+	f.issuers = []carta.Issuer{acme, globex, initech}
+
+	f.contacts["acme"] = carta.IssuerContact{BaseResource: carta.BaseResource{Id: "alice"}, Name: "Alice", Email: "alice@acme.test"}
+	f.documents["acme"] = []carta.IssuerDocument{{BaseResource: carta.BaseResource{Id: "consent-1"}, Name: "Board consent", Type: "board_consent"}}
+	f.boardMembers["acme"] = []carta.BoardMember{
+		{BaseResource: carta.BaseResource{Id: "bob"}, Name: "Bob", Email: "bob@acme.test", Title: "Chair"},
+		{BaseResource: carta.BaseResource{Id: "carol"}, Name: "Carol", Email: "carol@acme.test"},
+	}
+
+	f.portfolios = []fakePortfolio{
+		{
+			portfolio: carta.Portfolio{Id: "growth", Name: "Growth"},
+			members:   []carta.Issuer{acme, globex},
+			firms:     []carta.InvestorFirm{fakeFirm("sequoia", "Sequoia")},
+		},
+		{
+			portfolio: carta.Portfolio{Id: "seed", Name: "Seed"},
+			members:   []carta.Issuer{initech},
+		},
+	}
+
+	f.firms = []carta.InvestorFirm{fakeFirm("sequoia", "Sequoia")}
+	f.firmMembers["sequoia"] = []carta.InvestorMember{{BaseResource: carta.BaseResource{Id: "dave"}, Name: "Dave", Email: "dave@sequoia.test", Role: "admin"}}
+	f.firmIssuers["sequoia"] = []carta.Issuer{acme}
+	f.firmContacts["sequoia"] = []carta.InvestorContact{
+		{BaseResource: carta.BaseResource{Id: "erin"}, Name: "Erin", Email: "erin@sequoia.test", PortfolioIds: []string{"growth"}},
+	}
+
+	return f
+}
+
+func fakeIssuer(id string, name string) carta.Issuer {
+	return carta.Issuer{BaseResource: carta.BaseResource{Id: id}, Name: name, Type: "company"}
+}
+
+func fakeFirm(id string, name string) carta.InvestorFirm {
+	return carta.InvestorFirm{BaseResource: carta.BaseResource{Id: id}, Name: name}
+}
+
+// baseURL is the base URL the connector reaches the fake Carta API at.
+func (f *fakeCarta) baseURL() string {
+	return f.server.URL + fakeBasePath
+}
+
+// connector returns a Carta connector syncing from the fake Carta API.
+func (f *fakeCarta) connector(t *testing.T, opts ...Option) *Carta {
+	t.Helper()
+
+	cartaConnector, err := New(testContext(t), "token", append([]Option{WithBaseURL(f.baseURL())}, opts...)...)
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	return cartaConnector
+}
+
+// requestCount returns the number of requests made to the path, relative to the base path.
+func (f *fakeCarta) requestCount(path string) int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.requests[path]
+}
+
+// update changes the served data while the fake is in use, e.g. between two syncs.
+func (f *fakeCarta) update(fn func(f *fakeCarta)) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	fn(f)
+}
+
+func (f *fakeCarta) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	inFlight := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+
+	for {
+		maxInFlight := atomic.LoadInt32(&f.maxInFlight)
+		if inFlight <= maxInFlight || atomic.CompareAndSwapInt32(&f.maxInFlight, maxInFlight, inFlight) {
+			break
+		}
+	}
+
+	time.Sleep(f.latency)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, fakeBasePath)
+	f.requests[path]++
+
+	if code, ok := f.failures[path]; ok {
+		w.WriteHeader(code)
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	switch {
+	case path == "issuers":
+		writePage(w, r, f.pageLimit, "issuers", f.issuers)
+	case len(segments) == 2 && segments[0] == "issuers":
+		issuer, ok := f.issuer(segments[1])
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"issuer": issuer})
+	case len(segments) == 3 && segments[0] == "issuers" && segments[2] == "contact":
+		contact, ok := f.contacts[segments[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"contact": contact})
+	case len(segments) == 3 && segments[0] == "issuers" && segments[2] == "documents":
+		writePage(w, r, f.pageLimit, "documents", f.documents[segments[1]])
+	case len(segments) == 3 && segments[0] == "issuers" && segments[2] == "board-members":
+		writePage(w, r, f.pageLimit, "boardMembers", f.boardMembers[segments[1]])
+	case path == "portfolios":
+		portfolios := make([]carta.Portfolio, 0, len(f.portfolios))
+		for _, portfolio := range f.portfolios {
+			portfolios = append(portfolios, portfolio.portfolio)
+		}
+		writePage(w, r, f.pageLimit, "portfolios", portfolios)
+	case len(segments) == 3 && segments[0] == "portfolios" && segments[2] == "issuers":
+		portfolio, ok := f.portfolio(segments[1])
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writePage(w, r, f.pageLimit, "issuers", portfolio.members)
+	case len(segments) == 3 && segments[0] == "portfolios" && segments[2] == "firms":
+		portfolio, ok := f.portfolio(segments[1])
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writePage(w, r, f.pageLimit, "firms", portfolio.firms)
+	case path == "investors/firms":
+		writePage(w, r, f.pageLimit, "firms", f.firms)
+	case len(segments) == 4 && path == "investors/firms/"+segments[2]+"/members":
+		writePage(w, r, f.pageLimit, "members", f.firmMembers[segments[2]])
+	case len(segments) == 4 && path == "investors/firms/"+segments[2]+"/issuers":
+		writePage(w, r, f.pageLimit, "issuers", f.firmIssuers[segments[2]])
+	case len(segments) == 4 && path == "investors/firms/"+segments[2]+"/contacts":
+		writePage(w, r, f.pageLimit, "contacts", f.firmContacts[segments[2]])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCarta) issuer(id string) (carta.Issuer, bool) {
+	for _, issuer := range append(append([]carta.Issuer{}, f.issuers...), f.hiddenIssuers...) {
+		if issuer.Id == id {
+			return issuer, true
+		}
+	}
+
+	return carta.Issuer{}, false
+}
+
+func (f *fakeCarta) portfolio(id string) (fakePortfolio, bool) {
+	for _, portfolio := range f.portfolios {
+		if portfolio.portfolio.Id == id {
+			return portfolio, true
+		}
+	}
+
+	return fakePortfolio{}, false
+}
+
+// writePage writes the page of items starting at the offset in the pageToken query parameter.
+func writePage[T any](w http.ResponseWriter, r *http.Request, limit int, key string, items []T) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	if offset > len(items) {
+		offset = len(items)
+	}
+
+	size, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || size <= 0 || size > limit {
+		size = limit
+	}
+
+	end := offset + size
+	if end > len(items) {
+		end = len(items)
+	}
+
+	next := ""
+	if end < len(items) {
+		next = strconv.Itoa(end)
+	}
+
+	page := items[offset:end]
+	if page == nil {
+		page = []T{}
+	}
+
+	writeJSON(w, map[string]interface{}{
+		key:             page,
+		"nextPageToken": next,
+		"totalCount":    len(items),
+	})
+}
+
+// writeJSON writes the value as JSON, leaving out the zero valued fields of Carta models the way
+// Carta omits fields it has no value for.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(omitZeroFields(generic))
+}
+
+// omitZeroFields drops the object fields holding zero values.
+func omitZeroFields(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			switch field := field.(type) {
+			case nil:
+				delete(value, key)
+			case string:
+				if field == "" {
+					delete(value, key)
+				}
+			case float64:
+				if field == 0 {
+					delete(value, key)
+				}
+			case bool:
+				if !field {
+					delete(value, key)
+				}
+			default:
+				value[key] = omitZeroFields(field)
+			}
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = omitZeroFields(value[i])
+		}
+	}
+
+	return v
+}
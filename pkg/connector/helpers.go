@@ -1,21 +1,46 @@
 package connector
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/ConductorOne/baton-carta/pkg/carta"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var ResourcesPageSize = 50
 
+// parsePageToken restores the pagination bag from the token, an empty token starts from the beginning.
+// Tokens come from stored sync state, so anything that doesn't decode to a usable bag is rejected or reset.
 func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, error) {
+	if resourceID == nil {
+		resourceID = &v2.ResourceId{}
+	}
+
 	b := &pagination.Bag{}
-	err := b.Unmarshal(i)
+	err := b.Unmarshal(strings.TrimSpace(i))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf(
+			"carta-connector: unable to parse page token for %s, it may be corrupt or from an incompatible version, run a full resync: %w",
+			resourceID.ResourceType,
+			err,
+		)
 	}
 
 	if b.Current() == nil {
+		// drop states left behind without a current state, e.g. "null" or a hand edited token
+		b = &pagination.Bag{}
 		b.Push(pagination.PageState{
 			ResourceTypeID: resourceID.ResourceType,
 			ResourceID:     resourceID.Resource,
@@ -25,12 +50,348 @@ func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, error
 	return b, nil
 }
 
-func mapIssuerIds(issuers []carta.Issuer) []string {
-	ids := make([]string, len(issuers))
+// paginate returns the page of items starting at the offset encoded in token, along with the token for the next page.
+// It is used where the whole set is known upfront (e.g. entitlements) but may be too big to return at once.
+func paginate[T any](items []T, token string, size int) ([]T, string, error) {
+	offset := 0
+	if token != "" {
+		var err error
+		offset, err = strconv.Atoi(token)
+		if err != nil || offset < 0 || offset > len(items) {
+			return nil, "", fmt.Errorf("carta-connector: invalid page token %q", token)
+		}
+	}
+
+	if size <= 0 || offset+size >= len(items) {
+		return items[offset:], "", nil
+	}
+
+	return items[offset : offset+size], strconv.Itoa(offset + size), nil
+}
+
+// pageSize returns the page size configured for listings of the resource type.
+func (so syncOptions) pageSize(resourceTypeID string) int {
+	if size, ok := so.pageSizes[resourceTypeID]; ok && size > 0 {
+		return size
+	}
+
+	return ResourcesPageSize
+}
+
+// uniqueIds drops empty and repeated ids, keeping the first occurrence order. Ids repeated in another
+// casing count as repeated, the first occurrence is kept as is.
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	rv := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		key := carta.NormalizeId(id)
+		if key == "" {
+			continue
+		}
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		rv = append(rv, id)
+	}
+
+	return rv
+}
+
+// sortedUniqueIds drops empty and repeated ids and sorts the rest, so profiles built from them are stable across syncs.
+func sortedUniqueIds(ids []string) []string {
+	rv := uniqueIds(ids)
+	sort.Strings(rv)
+
+	return rv
+}
+
+// issuerResourceType returns the resource type an issuer is synced as, funds are split
+// into their own resource type when configured.
+func (so syncOptions) issuerResourceType(issuer *carta.Issuer) *v2.ResourceType {
+	if so.splitFunds && strings.EqualFold(strings.TrimSpace(issuer.Type), "fund") {
+		return resourceTypeFund
+	}
+
+	return resourceTypeIssuer
+}
+
+// ensureListed replays the top level listings the current run didn't list, so grants of a sync resumed
+// past them resolve against the same listed state as a sync that ran through.
+func (so syncOptions) ensureListed(ctx context.Context) error {
+	var resourceTypeIDs []string
+	for _, resourceType := range so.issuerResourceTypes() {
+		resourceTypeIDs = append(resourceTypeIDs, resourceType.Id)
+	}
+
+	return so.run.ensureListed(ctx, so, append(resourceTypeIDs, resourceTypePortfolio.Id, resourceTypeInvestor.Id)...)
+}
+
+// issuerResourceTypes returns every resource type issuers may be synced as.
+func (so syncOptions) issuerResourceTypes() []*v2.ResourceType {
+	if so.splitFunds {
+		return []*v2.ResourceType{resourceTypeIssuer, resourceTypeFund}
+	}
+
+	return []*v2.ResourceType{resourceTypeIssuer}
+}
+
+// resourceId returns the resource id of a Carta id, with the configured prefix.
+func (so syncOptions) resourceId(cartaId string) string {
+	return so.idPrefix + cartaId
+}
+
+// cartaId returns the Carta id of a resource id, without the configured prefix.
+func (so syncOptions) cartaId(resourceId string) string {
+	return strings.TrimPrefix(resourceId, so.idPrefix)
+}
+
+// largeStakeholderCount reports whether the issuer has more stakeholders than the warning threshold.
+func (so syncOptions) largeStakeholderCount(issuer *carta.Issuer) bool {
+	return so.stakeholderWarningThreshold > 0 && issuer.StakeholderCount > so.stakeholderWarningThreshold
+}
+
+// pageError returns the error of a failed List page, or logs it and returns nil when the connector is
+// configured to continue past page errors. The failed page token is logged so the sync can be resumed.
+func (so syncOptions) pageError(ctx context.Context, resourceTypeID string, pageToken string, err error) error {
+	if !so.continueOnPageError {
+		return err
+	}
+
+	so.logLevels.Logger(ctx, carta.LogComponentPagination).Warn(
+		"carta-connector: skipping remaining pages after page fetch failure",
+		zap.String("resource_type", resourceTypeID),
+		zap.String("page_token", pageToken),
+		zap.Error(err),
+	)
+
+	return nil
+}
+
+// listPageToken restores the pagination bag of a top level List call. The first call of a listing
+// is recorded with the sync run, and starts from the configured start token of the resource type, if any.
+func (so syncOptions) listPageToken(ctx context.Context, token string, resourceTypeID string) (*pagination.Bag, error) {
+	if strings.TrimSpace(token) == "" {
+		so.run.begin(ctx, so, resourceTypeID)
+
+		if startToken := so.startTokens[resourceTypeID]; startToken != "" {
+			bag := &pagination.Bag{}
+			bag.Push(pagination.PageState{
+				ResourceTypeID: resourceTypeID,
+				Token:          startToken,
+			})
+
+			return bag, nil
+		}
+	}
+
+	return parsePageToken(token, &v2.ResourceId{ResourceType: resourceTypeID})
+}
+
+// normalizeCountryCode converts ISO-3166 alpha-2, alpha-3 or numeric codes into alpha-2,
+// returning the trimmed input when it can't be recognised.
+func normalizeCountryCode(country string) string {
+	country = strings.TrimSpace(country)
+
+	if code, ok := countryCodes[strings.ToUpper(country)]; ok {
+		return code
+	}
+
+	return country
+}
+
+// normalizeDomain returns the lowercased host of a website without the www prefix,
+// or empty string if the website is missing or invalid.
+func normalizeDomain(website string) string {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return ""
+	}
+
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+
+	parsed, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if host == "" || !strings.Contains(host, ".") {
+		return ""
+	}
+
+	return host
+}
+
+// validateBaseURL checks that the Carta API base URL is an absolute http(s) URL.
+func validateBaseURL(baseURL string) error {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("carta-connector: malformed base URL %q: %w", baseURL, err)
+	}
+
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("carta-connector: base URL %q must be an absolute http(s) URL", baseURL)
+	}
+
+	return nil
+}
+
+// dateLayouts are the date formats accepted from Carta, tried in order.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+}
+
+// normalizeDate parses a date in any of the accepted formats and returns it as RFC3339.
+func normalizeDate(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC().Format(time.RFC3339), nil
+		}
+	}
+
+	return "", fmt.Errorf("carta-connector: unrecognised date format %q", raw)
+}
+
+// resourceDisplayName prefers the friendlier display name and falls back to the legal name.
+func resourceDisplayName(displayName string, legalName string) string {
+	if displayName != "" {
+		return displayName
+	}
+
+	return legalName
+}
+
+// maxExactProfileInt is the largest integer a profile number, stored as float64, represents exactly.
+const maxExactProfileInt = 1 << 53
+
+// exactProfileNumber returns the integer as a profile value, large integers that a float64 profile
+// number can't represent exactly are stored as their decimal string instead.
+func exactProfileNumber(n int64) interface{} {
+	if n > maxExactProfileInt || n < -maxExactProfileInt {
+		return strconv.FormatInt(n, 10)
+	}
+
+	return n
+}
+
+const (
+	// grantSourceDirect marks issuer access granted to a firm directly.
+	grantSourceDirect = "direct"
+	// grantSourcePortfolio marks issuer access inherited through portfolio membership.
+	grantSourcePortfolio = "portfolio"
+)
+
+// structAnnotation returns an annotation carrying the fields. The facts the connector annotates grants and
+// pages with are its own, they have no message type among the SDK annotations, so they travel as structs.
+func structAnnotation(fields map[string]*structpb.Value) *structpb.Struct {
+	return &structpb.Struct{Fields: fields}
+}
+
+// grantSourceAnnotation tells direct issuer access apart from access through a portfolio, so
+// effective access can be computed when both apply.
+func grantSourceAnnotation(source string) *structpb.Struct {
+	return structAnnotation(map[string]*structpb.Value{
+		"grant_source": structpb.NewStringValue(source),
+	})
+}
+
+// stakeholderWarningAnnotation warns, on the page listing the issuer, that syncing its stakeholders would be
+// expensive, so operators can decide to skip it.
+func stakeholderWarningAnnotation(issuer *carta.Issuer, threshold int) *structpb.Struct {
+	return structAnnotation(map[string]*structpb.Value{
+		"warning":           structpb.NewStringValue("large_stakeholder_count"),
+		"issuer_id":         structpb.NewStringValue(issuer.Id),
+		"stakeholder_count": structpb.NewNumberValue(float64(issuer.StakeholderCount)),
+		"threshold":         structpb.NewNumberValue(float64(threshold)),
+	})
+}
+
+// systemManagedGrantSourceAnnotation is the grant source annotation of a membership maintained by Carta,
+// flagged as immutable so it isn't offered for revocation. Readers pick the first annotation of a message
+// type, so the flag goes on the grant source struct rather than on a struct of its own.
+func systemManagedGrantSourceAnnotation(source string) *structpb.Struct {
+	annotation := grantSourceAnnotation(source)
+	annotation.Fields["immutable"] = structpb.NewBoolValue(true)
+
+	return annotation
+}
+
+// newUserResource builds a user resource with the provided profile and status under the given parent.
+func newUserResource(
+	name string,
+	id string,
+	resourceType *v2.ResourceType,
+	profile map[string]interface{},
+	status v2.UserTrait_Status_Status,
+	parentResourceID *v2.ResourceId,
+) (*v2.Resource, error) {
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithStatus(status),
+	}
+
+	resource, err := rs.NewUserResource(
+		name,
+		resourceType,
+		id,
+		userTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// ErrResourceCapReached stops a sync that emitted more resources than the configured cap.
+var ErrResourceCapReached = errors.New("carta-connector: maximum number of resources per sync reached")
+
+// resourceCap counts the resources emitted across all resource types during a sync.
+type resourceCap struct {
+	mtx     sync.Mutex
+	max     int
+	emitted int
+}
+
+// reset starts counting the resources of a new sync.
+func (rc *resourceCap) reset() {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+
+	rc.emitted = 0
+}
+
+// countResources adds a page of listed resources to the sync total, failing the sync once the
+// configured cap is exceeded.
+func (so syncOptions) countResources(resourceTypeID string, count int) error {
+	if so.resourceCap == nil {
+		return nil
+	}
+
+	so.resourceCap.mtx.Lock()
+	defer so.resourceCap.mtx.Unlock()
 
-	for i, issuer := range issuers {
-		ids[i] = issuer.Id
+	so.resourceCap.emitted += count
+	if so.resourceCap.emitted > so.resourceCap.max {
+		return fmt.Errorf("%w: %d resources exceed the cap of %d while listing %s", ErrResourceCapReached, so.resourceCap.emitted, so.resourceCap.max, resourceTypeID)
 	}
 
-	return ids
+	return nil
 }
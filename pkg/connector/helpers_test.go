@@ -0,0 +1,171 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/protobuf/proto"
+)
+
+func FuzzParsePageToken(f *testing.F) {
+	bag := &pagination.Bag{}
+	bag.Push(pagination.PageState{ResourceTypeID: resourceTypePortfolio.Id})
+	bag.Push(pagination.PageState{ResourceTypeID: resourceTypeIssuer.Id, ResourceID: "growth", Token: "next"})
+	valid, err := bag.Marshal()
+	if err != nil {
+		f.Fatalf("failed to marshal a bag: %v", err)
+	}
+
+	for _, seed := range []string{
+		// empty tokens start from the beginning
+		"",
+		"  \n",
+		// tokens decoding to a bag without a current state
+		"null",
+		"{}",
+		`{"states":[]}`,
+		`{"states":null,"current_state":null}`,
+		// malformed tokens, which the error hints to resync from
+		"{",
+		"not a token",
+		`{"states":"portfolio"}`,
+		`{"current_state":{"token":1}}`,
+		"\x00\xff",
+		valid,
+		valid[:len(valid)/2],
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, token string) {
+		resourceID := &v2.ResourceId{ResourceType: resourceTypeIssuer.Id}
+
+		b, err := parsePageToken(token, resourceID)
+		if err != nil {
+			if b != nil {
+				t.Errorf("parsePageToken(%q) returned a bag along with error %v", token, err)
+			}
+
+			if !strings.Contains(err.Error(), "run a full resync") {
+				t.Errorf("parsePageToken(%q) error = %v, want it to hint at a full resync", token, err)
+			}
+
+			return
+		}
+
+		if b == nil || b.Current() == nil {
+			t.Fatalf("parsePageToken(%q) returned a bag without a current state", token)
+		}
+
+		// a usable bag can be paged through and stored again
+		if _, err := b.NextToken(""); err != nil {
+			t.Errorf("NextToken() of the bag parsed from %q error = %v", token, err)
+		}
+
+		if _, err := b.Marshal(); err != nil {
+			t.Errorf("Marshal() of the bag parsed from %q error = %v", token, err)
+		}
+	})
+}
+
+func TestNewUserResourceMatchesTraitAssembly(t *testing.T) {
+	parent := &v2.ResourceId{ResourceType: resourceTypePortfolio.Id, Resource: "growth"}
+	for _, tc := range []struct {
+		name         string
+		resourceType *v2.ResourceType
+		profile      map[string]interface{}
+		status       v2.UserTrait_Status_Status
+		parent       *v2.ResourceId
+	}{
+		{name: "issuer", resourceType: resourceTypeIssuer, profile: map[string]interface{}{"issuer_id": "acme", "issuer_fund_admin": true}},
+		{name: "investor under a parent", resourceType: resourceTypeInvestor, profile: map[string]interface{}{"investor_id": "sequoia"}, parent: parent},
+		{name: "disabled contact", resourceType: resourceTypeInvestorContact, profile: map[string]interface{}{"contact_id": "erin"}, status: v2.UserTrait_Status_STATUS_DISABLED},
+		{name: "empty profile", resourceType: resourceTypeIssuer, profile: map[string]interface{}{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := newUserResource("Name", "id", tc.resourceType, tc.profile, tc.status, tc.parent)
+			if err != nil {
+				t.Fatalf("newUserResource() error = %v", err)
+			}
+
+			// the trait assembly each builder carried before the helper
+			want, err := rs.NewUserResource(
+				"Name",
+				tc.resourceType,
+				"id",
+				[]rs.UserTraitOption{
+					rs.WithUserProfile(tc.profile),
+					rs.WithStatus(tc.status),
+				},
+				rs.WithParentResourceID(tc.parent),
+			)
+			if err != nil {
+				t.Fatalf("NewUserResource() error = %v", err)
+			}
+
+			// the trait is packed with its profile map in no particular order, so it's compared unpacked
+			gotTrait, err := rs.GetUserTrait(got)
+			if err != nil {
+				t.Fatalf("newUserResource() has no user trait: %v", err)
+			}
+
+			wantTrait, err := rs.GetUserTrait(want)
+			if err != nil {
+				t.Fatalf("NewUserResource() has no user trait: %v", err)
+			}
+
+			if !proto.Equal(gotTrait, wantTrait) {
+				t.Errorf("newUserResource() trait = %v, want %v", gotTrait, wantTrait)
+			}
+
+			if len(got.Annotations) != len(want.Annotations) {
+				t.Errorf("newUserResource() has %d annotations, want %d", len(got.Annotations), len(want.Annotations))
+			}
+
+			got.Annotations, want.Annotations = nil, nil
+			if !proto.Equal(got, want) {
+				t.Errorf("newUserResource() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCountryCode(t *testing.T) {
+	for country, want := range map[string]string{
+		"US":     "US",
+		" us ":   "US",
+		"GBR":    "GB",
+		"deu":    "DE",
+		"840":    "US",
+		"250":    "FR",
+		"SS":     "SS",
+		"CUW":    "CW",
+		"":       "",
+		"   ":    "",
+		"UK":     "UK",
+		"XK":     "XK",
+		"ZZ":     "ZZ",
+		"USA!":   "USA!",
+		"84":     "84",
+		"Canada": "Canada",
+	} {
+		if got := normalizeCountryCode(country); got != want {
+			t.Errorf("normalizeCountryCode(%q) = %q, want %q", country, got, want)
+		}
+	}
+}
+
+func TestCountryCodesAreUnique(t *testing.T) {
+	if len(countryCodes) != 3*len(isoCountries) {
+		t.Errorf("%d country codes for %d countries, some code is listed twice", len(countryCodes), len(isoCountries))
+	}
+
+	for _, country := range isoCountries {
+		if len(country[0]) != 2 || len(country[1]) != 3 || len(country[2]) != 3 {
+			t.Errorf("country %v doesn't have alpha-2, alpha-3 and numeric codes", country)
+		}
+	}
+}
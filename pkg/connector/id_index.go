@@ -0,0 +1,70 @@
+package connector
+
+import (
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+)
+
+// idIndex maps the normalized ids of the resources listed during a sync to the ids Carta listed them
+// with, so an id another endpoint returns in a different casing resolves to the synced resource.
+type idIndex struct {
+	mtx sync.RWMutex
+	// ids maps each resource type to the listed ids, keyed by normalized id.
+	ids map[string]map[string]string
+}
+
+func newIdIndex() *idIndex {
+	return &idIndex{
+		ids: make(map[string]map[string]string),
+	}
+}
+
+// add records the id of a listed resource, the first listing of an id wins.
+func (x *idIndex) add(resourceTypeID string, id string) {
+	key := carta.NormalizeId(id)
+	if key == "" {
+		return
+	}
+
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	if x.ids[resourceTypeID] == nil {
+		x.ids[resourceTypeID] = make(map[string]string)
+	}
+
+	if _, ok := x.ids[resourceTypeID][key]; !ok {
+		x.ids[resourceTypeID][key] = id
+	}
+}
+
+// reset drops the listed ids.
+func (x *idIndex) reset() {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	x.ids = make(map[string]map[string]string)
+}
+
+// resolve returns the id the resource was listed with, or the id itself when no such resource was listed.
+func (x *idIndex) resolve(resourceTypeID string, id string) string {
+	x.mtx.RLock()
+	defer x.mtx.RUnlock()
+
+	if listed, ok := x.ids[resourceTypeID][carta.NormalizeId(id)]; ok {
+		return listed
+	}
+
+	return id
+}
+
+// has reports whether a resource of the type was listed with the id, in any casing.
+func (x *idIndex) has(resourceTypeID string, id string) bool {
+	x.mtx.RLock()
+	defer x.mtx.RUnlock()
+
+	_, ok := x.ids[resourceTypeID][carta.NormalizeId(id)]
+
+	return ok
+}
@@ -3,41 +3,72 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ConductorOne/baton-carta/pkg/carta"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
+const investmentEntitlement = "investment"
+
+// administersEntitlement is granted to the issuers managed by the investor firm.
+const administersEntitlement = "administers"
+
+// roles a user can hold within an investor firm.
+var investorMemberRoles = []string{"admin", "analyst", "viewer"}
+
 type investorResourceType struct {
 	resourceType *v2.ResourceType
 	client       *carta.Client
+	syncOptions  syncOptions
+	pageSizer    *pageSizer
 }
 
 func (o *investorResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 	return o.resourceType
 }
 
-// Create a new connector resource for an Carta Investor.
-func investorResource(ctx context.Context, investor *carta.InvestorFirm, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+// Create a new connector resource for an Carta Investor (Firm grouping its users).
+func investorResource(ctx context.Context, so syncOptions, investor *carta.InvestorFirm, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"login":       investor.Name,
-		"investor_id": investor.Id,
+		"investor_name": investor.Name,
+		"investor_id":   investor.Id,
+	}
+
+	// firm metrics are only returned for some firms
+	if aum := strings.TrimSpace(investor.AssetsUnderManagement.String()); aum != "" {
+		profile["investor_assets_under_management"] = aum
+	}
+
+	if investor.FundCount > 0 {
+		profile["investor_fund_count"] = exactProfileNumber(int64(investor.FundCount))
+	}
+
+	if investor.FoundedYear > 0 {
+		profile["investor_founded_year"] = investor.FoundedYear
 	}
 
-	investorTraitOptions := []rs.UserTraitOption{
-		rs.WithUserProfile(profile),
-		rs.WithStatus(v2.UserTrait_Status_STATUS_UNSPECIFIED),
+	investorTraitOptions := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
 	}
 
-	resource, err := rs.NewUserResource(
-		investor.Name,
+	resource, err := rs.NewGroupResource(
+		so.displayName(resourceTypeInvestor, displayNameData{Name: investor.Name, LegalName: investor.Name, Id: investor.Id}),
 		resourceTypeInvestor,
-		investor.Id,
+		so.resourceId(investor.Id),
 		investorTraitOptions,
 		rs.WithParentResourceID(parentResourceID),
+		// sync firm users and contacts as children of the firm
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: resourceTypeInvestorMember.Id}),
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: resourceTypeInvestorContact.Id}),
 	)
 
 	if err != nil {
@@ -48,17 +79,32 @@ func investorResource(ctx context.Context, investor *carta.InvestorFirm, parentR
 }
 
 func (o *investorResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeInvestor.Id})
+	bag, err := o.syncOptions.listPageToken(ctx, token.Token, resourceTypeInvestor.Id)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	start := time.Now()
 	investors, nextToken, err := o.client.GetInvestors(
 		ctx,
-		carta.PaginationParams{Size: ResourcesPageSize, After: bag.PageToken()},
+		carta.PaginationParams{Size: o.pageSizer.current(), After: bag.PageToken(), UpdatedSince: o.syncOptions.updatedSince},
 	)
+	o.pageSizer.observe(time.Since(start), err)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("carta-connector: failed to list investors: %w", err)
+		// a token without investor visibility is told apart from a tenant without investors
+		if carta.IsAccessDenied(err) {
+			ctxzap.Extract(ctx).Warn(
+				"carta-connector: access token has no visibility of investor firms, investors are hidden rather than absent",
+				zap.Error(err),
+			)
+			return nil, "", nil, nil
+		}
+
+		return nil, "", nil, o.syncOptions.pageError(ctx, resourceTypeInvestor.Id, bag.PageToken(), fmt.Errorf("carta-connector: failed to list investors: %w", err))
+	}
+
+	if len(investors) == 0 && bag.PageToken() == "" {
+		ctxzap.Extract(ctx).Info("carta-connector: no investor firms are visible to the access token")
 	}
 
 	pageToken, err := bag.NextToken(nextToken)
@@ -69,29 +115,246 @@ func (o *investorResourceType) List(ctx context.Context, parentId *v2.ResourceId
 	var rv []*v2.Resource
 	for _, investor := range investors {
 		investorCopy := investor
-		ir, err := investorResource(ctx, &investorCopy, parentId)
+		ir, err := investorResource(ctx, o.syncOptions, &investorCopy, parentId)
 
 		if err != nil {
 			return nil, "", nil, err
 		}
 
+		o.syncOptions.listedIds.add(resourceTypeInvestor.Id, investorCopy.Id)
 		rv = append(rv, ir)
 	}
 
+	if err := o.syncOptions.countResources(resourceTypeInvestor.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
 	return rv, pageToken, nil, nil
 }
 
 func (o *investorResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var rv []*v2.Entitlement
+	membershipOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeInvestorMember),
+		ent.WithDisplayName(fmt.Sprintf("%s Investor %s", resource.DisplayName, memberEntitlement)),
+		ent.WithDescription(fmt.Sprintf("Member of %s investor firm in Carta", resource.DisplayName)),
+	}
+
+	// create membership entitlement
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		memberEntitlement,
+		membershipOptions...,
+	))
+
+	investmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(o.syncOptions.issuerResourceTypes()...),
+		ent.WithDisplayName(fmt.Sprintf("%s Investor %s", resource.DisplayName, investmentEntitlement)),
+		ent.WithDescription(fmt.Sprintf("Issuers %s investor firm invests in on Carta", resource.DisplayName)),
+	}
+
+	// create investment entitlement
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		investmentEntitlement,
+		investmentOptions...,
+	))
+
+	administersOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(o.syncOptions.issuerResourceTypes()...),
+		ent.WithDisplayName(fmt.Sprintf("%s Investor %s", resource.DisplayName, administersEntitlement)),
+		ent.WithDescription(fmt.Sprintf("Issuers managed by %s investor firm on Carta", resource.DisplayName)),
+	}
+
+	// create administers entitlement
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		administersEntitlement,
+		administersOptions...,
+	))
+
+	for _, role := range investorMemberRoles {
+		roleOptions := []ent.EntitlementOption{
+			ent.WithGrantableTo(resourceTypeInvestorMember),
+			ent.WithDisplayName(fmt.Sprintf("%s Investor %s", resource.DisplayName, role)),
+			ent.WithDescription(fmt.Sprintf("%s role in %s investor firm in Carta", role, resource.DisplayName)),
+		}
+
+		// create role entitlement
+		rv = append(rv, ent.NewAssignmentEntitlement(
+			resource,
+			role,
+			roleOptions...,
+		))
+	}
+
+	page, nextToken, err := paginate(rv, token.Token, token.Size)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return page, nextToken, nil, nil
 }
 
 func (o *investorResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	if err := o.syncOptions.ensureListed(ctx); err != nil {
+		return nil, "", nil, err
+	}
+
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(token.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// walk firm members first, then the issuers the firm invests in
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeIssuer.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeInvestorMember.Id})
+	}
+
+	var rv []*v2.Grant
+	var nextToken string
+	switch bag.ResourceTypeID() {
+	case resourceTypeInvestorMember.Id:
+		rv, nextToken, err = o.memberGrants(ctx, resource, bag.PageToken())
+	case resourceTypeIssuer.Id:
+		rv, nextToken, err = o.issuerGrants(ctx, resource, bag.PageToken())
+	default:
+		return nil, "", nil, fmt.Errorf("carta-connector: unexpected resource type in investor grants page token: %s", bag.ResourceTypeID())
+	}
+
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+// memberGrants creates membership and role grants for a page of firm members.
+func (o *investorResourceType) memberGrants(ctx context.Context, resource *v2.Resource, after string) ([]*v2.Grant, string, error) {
+	firmId := o.syncOptions.cartaId(resource.Id.Resource)
+	members, nextToken, err := o.client.GetInvestorMembers(
+		ctx,
+		firmId,
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeInvestorMember.Id), After: after},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("carta-connector: failed to list investor members: %w", err)
+	}
+
+	// a member repeated within the page must not yield duplicate grants
+	seen := make(map[string]struct{}, len(members))
+
+	var rv []*v2.Grant
+	for _, member := range members {
+		if _, ok := seen[member.Id]; ok {
+			continue
+		}
+		seen[member.Id] = struct{}{}
+
+		memberCopy := member
+		mr, err := investorMemberResource(ctx, o.syncOptions, firmId, &memberCopy, resource.Id)
+		if err != nil {
+			return nil, "", err
+		}
+
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				memberEntitlement,
+				mr.Id,
+			),
+		)
+
+		role := strings.ToLower(member.Role)
+		if !isInvestorMemberRole(role) {
+			continue
+		}
+
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				role,
+				mr.Id,
+			),
+		)
+	}
+
+	return rv, nextToken, nil
+}
+
+// issuerGrants creates investment grants for a page of issuers the firm invests in.
+func (o *investorResourceType) issuerGrants(ctx context.Context, resource *v2.Resource, after string) ([]*v2.Grant, string, error) {
+	issuers, nextToken, err := o.client.GetIssuersForInvestor(
+		ctx,
+		o.syncOptions.cartaId(resource.Id.Resource),
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeIssuer.Id), After: after},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("carta-connector: failed to list investor issuers: %w", err)
+	}
+
+	// an issuer repeated within the page must not yield duplicate grants
+	seen := make(map[string]struct{}, len(issuers))
+
+	var rv []*v2.Grant
+	for _, issuer := range issuers {
+		key := carta.NormalizeId(issuer.Id)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		// the grant points at the issuer as it was listed, whatever casing the firm lists it in
+		issuerCopy := issuer
+		resourceType := o.syncOptions.issuerResourceType(&issuerCopy)
+
+		// lazy mode only lists portfolio members, other investments would point at an issuer that isn't synced
+		if o.syncOptions.lazyIssuers && !o.syncOptions.listedIds.has(resourceType.Id, issuer.Id) {
+			continue
+		}
+		principal := &v2.ResourceId{
+			ResourceType: resourceType.Id,
+			Resource:     o.syncOptions.resourceId(o.syncOptions.listedIds.resolve(resourceType.Id, issuer.Id)),
+		}
+
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				investmentEntitlement,
+				principal,
+				grant.WithAnnotation(grantSourceAnnotation(grantSourceDirect)),
+			),
+		)
+	}
+
+	return rv, nextToken, nil
+}
+
+func isInvestorMemberRole(role string) bool {
+	for _, r := range investorMemberRoles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
 }
 
-func investorBuilder(client *carta.Client) *investorResourceType {
+func investorBuilder(client *carta.Client, syncOptions syncOptions) *investorResourceType {
 	return &investorResourceType{
 		resourceType: resourceTypeInvestor,
 		client:       client,
+		syncOptions:  syncOptions,
+		pageSizer:    newPageSizer(syncOptions.adaptivePageSize, syncOptions.pageSize(resourceTypeInvestor.Id)),
 	}
 }
@@ -0,0 +1,164 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"go.uber.org/zap"
+)
+
+type investorContactResourceType struct {
+	resourceType *v2.ResourceType
+	client       *carta.Client
+	syncOptions  syncOptions
+}
+
+func (o *investorContactResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for a named contact of a Carta Investor firm.
+func investorContactResource(ctx context.Context, so syncOptions, firmId string, contact *carta.InvestorContact, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"login":                 contact.Email,
+		"email":                 contact.Email,
+		"contact_id":            contact.Id,
+		"contact_portfolio_ids": strings.Join(sortedUniqueIds(contact.PortfolioIds), ","),
+	}
+
+	return newUserResource(
+		so.displayName(resourceTypeInvestorContact, displayNameData{Name: contact.Name, LegalName: contact.Name, Id: contact.Id}),
+		so.resourceId(investorContactId(firmId, contact.Id)),
+		resourceTypeInvestorContact,
+		profile,
+		v2.UserTrait_Status_STATUS_UNSPECIFIED,
+		parentResourceID,
+	)
+}
+
+func (o *investorContactResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// contacts are only listed under their investor firm
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeInvestorContact.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	firmId := o.syncOptions.cartaId(parentId.Resource)
+	contacts, nextToken, err := o.client.GetInvestorContacts(
+		ctx,
+		firmId,
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeInvestorContact.Id), After: bag.PageToken()},
+	)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("carta-connector: failed to list investor contacts: %w", err)
+	}
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	for _, contact := range contacts {
+		contactCopy := contact
+		cr, err := investorContactResource(ctx, o.syncOptions, firmId, &contactCopy, parentId)
+
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, cr)
+	}
+
+	if err := o.syncOptions.countResources(resourceTypeInvestorContact.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+func (o *investorContactResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants creates portfolio membership grants for the portfolios the contact can access, as portfolios
+// don't know which firm contacts can access them. Contacts keep listing portfolios their firm lost access
+// to, so only the portfolios listed as shared with the contact's firm during the sync are granted.
+func (o *investorContactResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if err := o.syncOptions.ensureListed(ctx); err != nil {
+		return nil, "", nil, err
+	}
+
+	contactTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	portfolioIdsString, ok := rs.GetProfileStringValue(contactTrait.Profile, "contact_portfolio_ids")
+	if !ok {
+		return nil, "", nil, nil
+	}
+
+	if resource.ParentResourceId == nil {
+		return nil, "", nil, nil
+	}
+
+	firmId := o.syncOptions.cartaId(resource.ParentResourceId.Resource)
+	contactId, _ := rs.GetProfileStringValue(contactTrait.Profile, "contact_id")
+
+	var rv []*v2.Grant
+	for _, id := range uniqueIds(strings.Split(portfolioIdsString, ",")) {
+		if !o.syncOptions.run.portfolioAccess().shared(id, firmId) {
+			o.syncOptions.logLevels.Logger(ctx, carta.LogComponentGrants).Debug(
+				"carta-connector: portfolio isn't shared with the contact's firm, skipping the contact's membership",
+				zap.String("contact_id", contactId),
+				zap.String("portfolio_id", id),
+				zap.String("firm_id", firmId),
+			)
+			continue
+		}
+
+		portfolio := &v2.Resource{
+			Id: &v2.ResourceId{
+				ResourceType: resourceTypePortfolio.Id,
+				Resource:     o.syncOptions.resourceId(o.syncOptions.listedIds.resolve(resourceTypePortfolio.Id, id)),
+			},
+		}
+
+		rv = append(
+			rv,
+			grant.NewGrant(
+				portfolio,
+				memberEntitlement,
+				resource.Id,
+			),
+		)
+	}
+
+	return rv, "", nil, nil
+}
+
+// investorContactId scopes a contact id to its firm, as the same person may be a contact of several
+// firms while a resource has a single parent.
+func investorContactId(firmId string, contactId string) string {
+	return firmId + "/" + contactId
+}
+
+func investorContactBuilder(client *carta.Client, syncOptions syncOptions) *investorContactResourceType {
+	return &investorContactResourceType{
+		resourceType: resourceTypeInvestorContact,
+		client:       client,
+		syncOptions:  syncOptions,
+	}
+}
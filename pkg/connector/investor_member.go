@@ -0,0 +1,107 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type investorMemberResourceType struct {
+	resourceType *v2.ResourceType
+	client       *carta.Client
+	syncOptions  syncOptions
+}
+
+func (o *investorMemberResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for a user within a Carta Investor firm.
+func investorMemberResource(ctx context.Context, so syncOptions, firmId string, member *carta.InvestorMember, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"login":     member.Email,
+		"email":     member.Email,
+		"member_id": member.Id,
+		"role":      member.Role,
+	}
+
+	return newUserResource(
+		so.displayName(resourceTypeInvestorMember, displayNameData{Name: member.Name, LegalName: member.Name, Id: member.Id}),
+		so.resourceId(investorMemberId(firmId, member.Id)),
+		resourceTypeInvestorMember,
+		profile,
+		v2.UserTrait_Status_STATUS_UNSPECIFIED,
+		parentResourceID,
+	)
+}
+
+func (o *investorMemberResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// members are only listed under their investor firm
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeInvestorMember.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	firmId := o.syncOptions.cartaId(parentId.Resource)
+	members, nextToken, err := o.client.GetInvestorMembers(
+		ctx,
+		firmId,
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeInvestorMember.Id), After: bag.PageToken()},
+	)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("carta-connector: failed to list investor members: %w", err)
+	}
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	for _, member := range members {
+		memberCopy := member
+		mr, err := investorMemberResource(ctx, o.syncOptions, firmId, &memberCopy, parentId)
+
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, mr)
+	}
+
+	if err := o.syncOptions.countResources(resourceTypeInvestorMember.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+func (o *investorMemberResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *investorMemberResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// investorMemberId scopes a member id to its firm, as the same user may be a member of several firms,
+// with a role in each, while a resource has a single parent.
+func investorMemberId(firmId string, memberId string) string {
+	return firmId + "/" + memberId
+}
+
+func investorMemberBuilder(client *carta.Client, syncOptions syncOptions) *investorMemberResourceType {
+	return &investorMemberResourceType{
+		resourceType: resourceTypeInvestorMember,
+		client:       client,
+		syncOptions:  syncOptions,
+	}
+}
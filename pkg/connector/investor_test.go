@@ -0,0 +1,162 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+func TestFirmLosingPortfolioAccessRevokesGrants(t *testing.T) {
+	f := newFixtureCarta(t)
+	cartaConnector := f.connector(t)
+
+	first := runSync(t, cartaConnector)
+	assertSyncInvariants(t, first)
+
+	if !first.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeInvestor, "sequoia") {
+		t.Fatal("firm sequoia has no member grant on portfolio growth")
+	}
+
+	if !first.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeInvestorContact, investorContactId("sequoia", "erin")) {
+		t.Fatal("contact erin of firm sequoia has no member grant on portfolio growth")
+	}
+
+	// the firm loses access, while its contact still lists the portfolio
+	f.update(func(f *fakeCarta) {
+		f.portfolios[0].firms = nil
+	})
+
+	second := runSync(t, cartaConnector)
+	assertSyncInvariants(t, second)
+
+	if second.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeInvestor, "sequoia") {
+		t.Error("firm sequoia kept its member grant on portfolio growth after losing access")
+	}
+
+	if second.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeInvestorContact, investorContactId("sequoia", "erin")) {
+		t.Error("contact erin kept its member grant on portfolio growth after the firm lost access")
+	}
+
+	// the other grants are synced the same way again
+	secondGrants := make(map[string]struct{}, len(second.grants))
+	for _, g := range second.grants {
+		secondGrants[g.Id] = struct{}{}
+	}
+
+	for _, g := range first.grants {
+		if g.Entitlement.Resource.Id.Resource == "growth" && (g.Principal.Id.Resource == "sequoia" || g.Principal.Id.Resource == investorContactId("sequoia", "erin")) {
+			continue
+		}
+
+		if _, ok := secondGrants[g.Id]; !ok {
+			t.Errorf("grant %s of the first sync is missing from the second", g.Id)
+		}
+	}
+}
+
+func TestFirmMembersScopedToTheirFirm(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.firms = append(f.firms, fakeFirm("a16z", "Andreessen Horowitz"))
+
+	// dave works with both firms, holding another role in each
+	dave := f.firmMembers["sequoia"][0]
+	dave.Role = "viewer"
+	f.firmMembers["a16z"] = []carta.InvestorMember{dave}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for _, tc := range []struct {
+		firmId    string
+		role      string
+		otherRole string
+	}{
+		{firmId: "sequoia", role: "admin", otherRole: "viewer"},
+		{firmId: "a16z", role: "viewer", otherRole: "admin"},
+	} {
+		memberId := investorMemberId(tc.firmId, "dave")
+		member, ok := result.resources[resourceKey(&v2.ResourceId{ResourceType: resourceTypeInvestorMember.Id, Resource: memberId})]
+		if !ok {
+			t.Fatalf("member dave of %s was not synced", tc.firmId)
+		}
+
+		if parent := member.ParentResourceId; parent == nil || parent.Resource != tc.firmId {
+			t.Errorf("member dave of %s has parent %v", tc.firmId, parent)
+		}
+
+		if !result.hasGrant(resourceTypeInvestor, tc.firmId, memberEntitlement, resourceTypeInvestorMember, memberId) {
+			t.Errorf("member dave has no member grant on %s", tc.firmId)
+		}
+
+		if !result.hasGrant(resourceTypeInvestor, tc.firmId, tc.role, resourceTypeInvestorMember, memberId) {
+			t.Errorf("member dave has no %s grant on %s", tc.role, tc.firmId)
+		}
+
+		if result.hasGrant(resourceTypeInvestor, tc.firmId, tc.otherRole, resourceTypeInvestorMember, memberId) {
+			t.Errorf("member dave has the %s role of the other firm on %s", tc.otherRole, tc.firmId)
+		}
+	}
+}
+
+func TestFirmContactsScopedToTheirFirm(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.firms = append(f.firms, fakeFirm("a16z", "Andreessen Horowitz"))
+	f.portfolios[1].firms = []carta.InvestorFirm{fakeFirm("a16z", "Andreessen Horowitz")}
+
+	// erin is a contact of both firms, listing the portfolios of both
+	erin := f.firmContacts["sequoia"][0]
+	erin.PortfolioIds = []string{"growth", "seed"}
+	f.firmContacts["sequoia"] = []carta.InvestorContact{erin}
+	f.firmContacts["a16z"] = []carta.InvestorContact{erin}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for firmId, shared := range map[string]string{"sequoia": "growth", "a16z": "seed"} {
+		contactId := investorContactId(firmId, "erin")
+		contact, ok := result.resources[resourceKey(&v2.ResourceId{ResourceType: resourceTypeInvestorContact.Id, Resource: contactId})]
+		if !ok {
+			t.Fatalf("contact erin of %s was not synced", firmId)
+		}
+
+		if parent := contact.ParentResourceId; parent == nil || parent.Resource != firmId {
+			t.Errorf("contact erin of %s has parent %v", firmId, parent)
+		}
+
+		// each contact is only granted the portfolios shared with its own firm
+		for _, portfolioId := range []string{"growth", "seed"} {
+			want := portfolioId == shared
+			if got := result.hasGrant(resourceTypePortfolio, portfolioId, memberEntitlement, resourceTypeInvestorContact, contactId); got != want {
+				t.Errorf("contact erin of %s has a member grant on %s = %t, want %t", firmId, portfolioId, got, want)
+			}
+		}
+	}
+}
+
+func TestResumedSyncGrantsContactsTheirFirmsCurrentAccess(t *testing.T) {
+	f := newFixtureCarta(t)
+	full := runSync(t, f.connector(t))
+
+	erin, ok := full.resources[resourceKey(&v2.ResourceId{ResourceType: resourceTypeInvestorContact.Id, Resource: investorContactId("sequoia", "erin")})]
+	if !ok {
+		t.Fatal("contact erin was not synced")
+	}
+
+	// a sync resumed by another connector process after the listings grants the contact from the portfolio
+	// access it lists again, rather than none at all
+	contacts := resourceSyncer(t, f.connector(t), resourceTypeInvestorContact)
+	if grants := resourceGrants(t, contacts, erin); len(grants) != 1 || grants[0].Entitlement.Resource.Id.Resource != "growth" {
+		t.Errorf("the resumed sync granted contact erin %v, want its member grant on portfolio growth", grants)
+	}
+
+	// the firm losing access by the time the sync resumes revokes the grant
+	f.update(func(f *fakeCarta) {
+		f.portfolios[0].firms = nil
+	})
+
+	contacts = resourceSyncer(t, f.connector(t), resourceTypeInvestorContact)
+	if grants := resourceGrants(t, contacts, erin); len(grants) != 0 {
+		t.Errorf("the resumed sync granted contact erin %v after the firm lost access", grants)
+	}
+}
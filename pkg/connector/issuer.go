@@ -3,17 +3,31 @@ package connector
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/ConductorOne/baton-carta/pkg/carta"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	primaryContactEntitlement = "primary_contact"
+	boardEntitlement          = "board"
 )
 
 type issuerResourceType struct {
 	resourceType *v2.ResourceType
 	client       *carta.Client
+	syncOptions  syncOptions
+	pageSizer    *pageSizer
 }
 
 func (o *issuerResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -21,44 +35,201 @@ func (o *issuerResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 }
 
 // Create a new connector resource for an Carta Issuer (Company to invest in).
-func issuerResource(ctx context.Context, issuer *carta.Issuer, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+func issuerResource(ctx context.Context, so syncOptions, issuer *carta.Issuer, resourceType *v2.ResourceType, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"issuer_legal_name": issuer.Name,
 		"issuer_id":         issuer.Id,
 	}
 
-	issuerTraitOptions := []rs.UserTraitOption{
-		rs.WithUserProfile(profile),
-		rs.WithStatus(v2.UserTrait_Status_STATUS_UNSPECIFIED),
+	if alternateNames := issuerAlternateNames(issuer); len(alternateNames) > 0 {
+		profile["issuer_alternate_names"] = alternateNames
+	}
+
+	if country := normalizeCountryCode(issuer.Country); country != "" {
+		profile["issuer_country"] = country
+	}
+
+	// surrounding whitespace changes between syncs would otherwise show up as profile diffs
+	if state := strings.TrimSpace(issuer.State); state != "" {
+		profile["issuer_state"] = state
+	}
+
+	if issuerType := strings.TrimSpace(issuer.Type); issuerType != "" {
+		profile["issuer_type"] = issuerType
+	}
+
+	if issuer.IncorporationDate != "" {
+		incorporationDate, err := normalizeDate(issuer.IncorporationDate)
+		if err != nil {
+			ctxzap.Extract(ctx).Debug(
+				"carta-connector: omitting unparseable issuer incorporation date",
+				zap.String("issuer_id", issuer.Id),
+				zap.Error(err),
+			)
+		} else {
+			profile["issuer_incorporation_date"] = incorporationDate
+		}
+	}
+
+	if issuer.ManagingFirmId != "" {
+		profile["issuer_managing_firm_id"] = issuer.ManagingFirmId
+	}
+
+	// valuations are exact decimal strings, a float profile number would lose precision
+	if value := strings.TrimSpace(issuer.Last409AValue.String()); value != "" {
+		profile["issuer_last_409a_value"] = value
+	}
+
+	if valuation := strings.TrimSpace(issuer.PostMoneyValuation.String()); valuation != "" {
+		profile["issuer_post_money_valuation"] = valuation
+	}
+
+	if issuer.SecurityCount != nil {
+		profile["issuer_security_count"] = exactProfileNumber(int64(*issuer.SecurityCount))
+	}
+
+	// the SDK version in use has no trait to tell fund administrators apart, so they are marked on the profile
+	if isFundAdmin(issuer) {
+		profile["issuer_fund_admin"] = true
+	}
+
+	if issuer.ParentCompanyId != "" {
+		profile["issuer_parent_company_id"] = issuer.ParentCompanyId
+	}
+
+	if issuer.StakeholderCount > 0 {
+		profile["issuer_stakeholder_count"] = exactProfileNumber(int64(issuer.StakeholderCount))
+	}
+
+	// only publicly traded issuers carry a ticker
+	if ticker := strings.ToUpper(strings.TrimSpace(issuer.Ticker)); ticker != "" {
+		profile["issuer_ticker"] = ticker
+
+		if exchange := strings.TrimSpace(issuer.Exchange); exchange != "" {
+			profile["issuer_exchange"] = exchange
+		}
 	}
 
-	resource, err := rs.NewUserResource(
-		issuer.Name,
-		resourceTypeIssuer,
-		issuer.Id,
-		issuerTraitOptions,
-		rs.WithParentResourceID(parentResourceID),
+	if website := strings.TrimSpace(issuer.Website); website != "" {
+		profile["issuer_website"] = website
+	}
+
+	if domain := normalizeDomain(issuer.Website); domain != "" {
+		profile["issuer_domain"] = domain
+	}
+
+	resource, err := newUserResource(
+		so.displayName(resourceType, displayNameData{
+			Name:      resourceDisplayName(issuer.DisplayName, issuer.Name),
+			LegalName: issuer.Name,
+			Id:        issuer.Id,
+			Country:   normalizeCountryCode(issuer.Country),
+			State:     strings.TrimSpace(issuer.State),
+		}),
+		so.resourceId(issuer.Id),
+		resourceType,
+		profile,
+		v2.UserTrait_Status_STATUS_UNSPECIFIED,
+		parentResourceID,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// sync the primary contact and documents as children of the issuer
+	err = rs.WithAnnotation(
+		&v2.ChildResourceType{ResourceTypeId: resourceTypeIssuerContact.Id},
+		&v2.ChildResourceType{ResourceTypeId: resourceTypeIssuerDocument.Id},
+		&v2.ChildResourceType{ResourceTypeId: resourceTypeBoardMember.Id},
+	)(resource)
+	if err != nil {
+		return nil, err
+	}
+
 	return resource, nil
 }
 
+// isFundAdmin reports whether the issuer acts as a fund administrator.
+func isFundAdmin(issuer *carta.Issuer) bool {
+	for _, role := range issuer.Roles {
+		role = strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(role)))
+		if role == "fund_admin" || role == "fund_administrator" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// issuerAlternateNames returns the distinct alternate names of the issuer, leaving out its legal and display names.
+// The names are sorted, so a reordered listing doesn't change the profile.
+func issuerAlternateNames(issuer *carta.Issuer) []interface{} {
+	seen := map[string]struct{}{
+		strings.ToLower(strings.TrimSpace(issuer.Name)):        {},
+		strings.ToLower(strings.TrimSpace(issuer.DisplayName)): {},
+	}
+
+	var distinct []string
+	for _, name := range issuer.AlternateNames {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if _, ok := seen[key]; ok || name == "" {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		distinct = append(distinct, name)
+	}
+
+	sort.Strings(distinct)
+
+	names := make([]interface{}, 0, len(distinct))
+	for _, name := range distinct {
+		names = append(names, name)
+	}
+
+	return names
+}
+
 func (o *issuerResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeIssuer.Id})
+	bag, err := o.syncOptions.listPageToken(ctx, token.Token, o.resourceType.Id)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	// portfolio members missing from the issuer listing are listed from their portfolios once the issuer
+	// listing is done, without the issuer listing all issuers are
+	if strings.TrimSpace(token.Token) == "" {
+		current := bag.Pop()
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypePortfolio.Id})
+		if !o.syncOptions.lazyIssuers {
+			bag.Push(*current)
+		}
+	}
+
+	switch bag.ResourceTypeID() {
+	case o.resourceType.Id:
+		return o.listIssuers(ctx, parentId, bag)
+	case resourceTypePortfolio.Id:
+		return o.listPortfolioMembers(ctx, parentId, bag)
+	default:
+		return nil, "", nil, fmt.Errorf("carta-connector: unexpected resource type in issuer page token: %s", bag.ResourceTypeID())
+	}
+}
+
+// listIssuers lists a page of the issuers accessible to the user or investor.
+func (o *issuerResourceType) listIssuers(ctx context.Context, parentId *v2.ResourceId, bag *pagination.Bag) ([]*v2.Resource, string, annotations.Annotations, error) {
+	after := bag.PageToken()
+
+	start := time.Now()
 	issuers, nextToken, err := o.client.GetIssuers(
 		ctx,
-		carta.PaginationParams{Size: ResourcesPageSize, After: bag.PageToken()},
+		carta.PaginationParams{Size: o.pageSizer.current(), After: after, UpdatedSince: o.syncOptions.updatedSince},
 	)
+	o.pageSizer.observe(time.Since(start), err)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("carta-connector: failed to list issuers: %w", err)
+		return nil, "", nil, o.syncOptions.pageError(ctx, o.resourceType.Id, after, fmt.Errorf("carta-connector: failed to list issuers: %w", err))
 	}
 
 	pageToken, err := bag.NextToken(nextToken)
@@ -66,11 +237,23 @@ func (o *issuerResourceType) List(ctx context.Context, parentId *v2.ResourceId,
 		return nil, "", nil, err
 	}
 
+	isDuplicate := o.syncOptions.run.duplicateDetector(o.resourceType.Id).page(after)
+
 	var rv []*v2.Resource
+	var annos annotations.Annotations
 	for _, issuer := range issuers {
 		issuerCopy := issuer
-		ir, err := issuerResource(ctx, &issuerCopy, parentId)
 
+		// issuers and funds are listed by their own syncers
+		if o.syncOptions.issuerResourceType(&issuerCopy).Id != o.resourceType.Id {
+			continue
+		}
+
+		if isDuplicate(ctx, o.resourceType.Id, issuerCopy.Id) {
+			continue
+		}
+
+		ir, err := o.listedIssuerResource(ctx, &issuerCopy, parentId, &annos)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -78,20 +261,411 @@ func (o *issuerResourceType) List(ctx context.Context, parentId *v2.ResourceId,
 		rv = append(rv, ir)
 	}
 
-	return rv, pageToken, nil, nil
+	if err := o.syncOptions.countResources(o.resourceType.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+// listPortfolioMembers lists the issuers that are members of the synced portfolios and weren't listed yet,
+// so every portfolio grant points at a synced issuer. The bag walks the portfolio pages, each pushing the
+// member pages of its portfolios.
+func (o *issuerResourceType) listPortfolioMembers(ctx context.Context, parentId *v2.ResourceId, bag *pagination.Bag) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if bag.ResourceID() == "" {
+		portfolios, nextToken, err := o.client.GetPortfolios(
+			ctx,
+			carta.PaginationParams{
+				Size:       o.syncOptions.pageSize(resourceTypePortfolio.Id),
+				After:      bag.PageToken(),
+				NamePrefix: o.syncOptions.portfolioNamePrefix,
+			},
+		)
+		if err != nil {
+			return o.skipPortfolioMembersPage(ctx, bag, fmt.Errorf("carta-connector: failed to list portfolios: %w", err))
+		}
+
+		if err := bag.Next(nextToken); err != nil {
+			return nil, "", nil, err
+		}
+
+		// the members of the portfolios on the page are listed before the next page of portfolios
+		for _, portfolio := range portfolios {
+			if !hasNamePrefix(portfolio, o.syncOptions.portfolioNamePrefix) {
+				continue
+			}
+
+			bag.Push(pagination.PageState{ResourceTypeID: resourceTypePortfolio.Id, ResourceID: portfolio.Id})
+		}
+
+		pageToken, err := bag.Marshal()
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		return nil, pageToken, nil, nil
+	}
+
+	portfolioId := bag.ResourceID()
+	after := bag.PageToken()
+
+	start := time.Now()
+	members, nextToken, err := o.client.GetIssuersForPortfolio(ctx, portfolioId, carta.PaginationParams{Size: o.pageSizer.current(), After: after})
+	o.pageSizer.observe(time.Since(start), err)
+	if err != nil {
+		return o.skipPortfolioMembersPage(ctx, bag, fmt.Errorf("carta-connector: failed to list issuers of portfolio %s: %w", portfolioId, err))
+	}
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// issuers listed already, or members of several portfolios, are listed from the first page they are on
+	isRepeated := o.syncOptions.run.duplicateDetector(o.resourceType.Id).repeats(portfolioId + "/" + after)
+
+	var issuers []carta.Issuer
+	for _, member := range members {
+		if strings.EqualFold(strings.TrimSpace(member.MemberType), "firm") || carta.NormalizeId(member.Id) == "" {
+			continue
+		}
+
+		if isRepeated(member.Id) {
+			continue
+		}
+
+		issuers = append(issuers, member)
+	}
+
+	ids := make([]string, 0, len(issuers))
+	for _, issuer := range issuers {
+		ids = append(ids, issuer.Id)
+	}
+
+	if err := o.client.WarmIssuerCache(ctx, ids); err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	var annos annotations.Annotations
+	for _, member := range issuers {
+		issuer, ok, err := resolvePortfolioMember(ctx, o.client, o.syncOptions, carta.LogComponentPagination, portfolioId, member)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		// issuers and funds are listed by their own syncers
+		if !ok || o.syncOptions.issuerResourceType(&issuer).Id != o.resourceType.Id {
+			continue
+		}
+
+		ir, err := o.listedIssuerResource(ctx, &issuer, parentId, &annos)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, ir)
+	}
+
+	if err := o.syncOptions.countResources(o.resourceType.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+// skipPortfolioMembersPage returns the error of a failed page of the portfolio member listing, or moves on
+// to the next portfolio when the connector is configured to continue past page errors.
+func (o *issuerResourceType) skipPortfolioMembersPage(ctx context.Context, bag *pagination.Bag, err error) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if err := o.syncOptions.pageError(ctx, o.resourceType.Id, bag.PageToken(), err); err != nil {
+		return nil, "", nil, err
+	}
+
+	bag.Pop()
+
+	pageToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return nil, pageToken, nil, nil
+}
+
+// listedIssuerResource returns the resource of an issuer listed by the syncer, recording it as listed. Warnings
+// about the issuer are appended to the annotations of the page.
+func (o *issuerResourceType) listedIssuerResource(
+	ctx context.Context,
+	issuer *carta.Issuer,
+	parentId *v2.ResourceId,
+	annos *annotations.Annotations,
+) (*v2.Resource, error) {
+	if o.syncOptions.securityCounts {
+		securityCount, ok, err := o.client.GetSecurityCount(ctx, issuer.Id)
+		if err != nil {
+			return nil, fmt.Errorf("carta-connector: failed to count issuer securities: %w", err)
+		}
+
+		if ok {
+			issuer.SecurityCount = &securityCount
+		}
+	}
+
+	ir, err := issuerResource(ctx, o.syncOptions, issuer, o.resourceType, o.parentResourceId(ctx, issuer, parentId))
+	if err != nil {
+		return nil, err
+	}
+
+	if o.syncOptions.largeStakeholderCount(issuer) {
+		ctxzap.Extract(ctx).Warn(
+			"carta-connector: issuer has a large stakeholder count, syncing its stakeholders may be expensive",
+			zap.String("issuer_id", issuer.Id),
+			zap.Int("stakeholder_count", issuer.StakeholderCount),
+			zap.Int("threshold", o.syncOptions.stakeholderWarningThreshold),
+		)
+
+		annos.Append(stakeholderWarningAnnotation(issuer, o.syncOptions.stakeholderWarningThreshold))
+	}
+
+	o.syncOptions.listedIds.add(o.resourceType.Id, issuer.Id)
+
+	return ir, nil
 }
 
 func (o *issuerResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var rv []*v2.Entitlement
+	contactOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeIssuerContact),
+		ent.WithDisplayName(fmt.Sprintf("%s Issuer %s", resource.DisplayName, primaryContactEntitlement)),
+		ent.WithDescription(fmt.Sprintf("Primary admin contact of %s issuer in Carta", resource.DisplayName)),
+	}
+
+	// create primary contact entitlement
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		primaryContactEntitlement,
+		contactOptions...,
+	))
+
+	boardOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeBoardMember),
+		ent.WithDisplayName(fmt.Sprintf("%s Issuer %s", resource.DisplayName, boardEntitlement)),
+		ent.WithDescription(fmt.Sprintf("Member of the board of %s issuer in Carta", resource.DisplayName)),
+	}
+
+	// create board entitlement, granted by the board members
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		boardEntitlement,
+		boardOptions...,
+	))
+
+	page, nextToken, err := paginate(rv, token.Token, token.Size)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return page, nextToken, nil, nil
 }
 
 func (o *issuerResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	if err := o.syncOptions.ensureListed(ctx); err != nil {
+		return nil, "", nil, err
+	}
+
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(token.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// grant the managing firm and primary contact first, then walk the board members. Board members may sit
+	// on several boards, so their board grants come from each issuer rather than from the member resource.
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeBoardMember.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeIssuerContact.Id})
+	}
+
+	var rv []*v2.Grant
+	var nextToken string
+	switch bag.ResourceTypeID() {
+	case resourceTypeIssuerContact.Id:
+		rv, err = o.contactGrants(ctx, resource)
+	case resourceTypeBoardMember.Id:
+		rv, nextToken, err = o.boardGrants(ctx, resource, bag.PageToken())
+	default:
+		return nil, "", nil, fmt.Errorf("carta-connector: unexpected resource type in issuer grants page token: %s", bag.ResourceTypeID())
+	}
+
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+// contactGrants creates the administers grant of the managing firm and the primary contact grant.
+func (o *issuerResourceType) contactGrants(ctx context.Context, resource *v2.Resource) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+
+	// the managing firm administers the issuer, only the issuer knows its managing firm
+	issuerTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	firmId, ok := rs.GetProfileStringValue(issuerTrait.Profile, "issuer_managing_firm_id")
+
+	// in lazy mode the grant is only kept when the sync lists the firm, like the investments of firms
+	if o.syncOptions.lazyIssuers && !o.syncOptions.listedIds.has(resourceTypeInvestor.Id, firmId) {
+		ok = false
+	}
+
+	if ok && firmId != "" {
+		firm := &v2.Resource{
+			Id: &v2.ResourceId{
+				ResourceType: resourceTypeInvestor.Id,
+				Resource:     o.syncOptions.resourceId(o.syncOptions.listedIds.resolve(resourceTypeInvestor.Id, firmId)),
+			},
+		}
+
+		rv = append(
+			rv,
+			grant.NewGrant(
+				firm,
+				administersEntitlement,
+				resource.Id,
+			),
+		)
+	}
+
+	// the contact is fetched once per sync, the contact listing fetched it already
+	issuerId := o.syncOptions.cartaId(resource.Id.Resource)
+	contact, err := o.client.GetIssuerContact(ctx, issuerId)
+	if err != nil {
+		return nil, fmt.Errorf("carta-connector: failed to get issuer contact: %w", err)
+	}
+
+	if contact == nil {
+		return rv, nil
+	}
+
+	// create primary contact grant
+	rv = append(
+		rv,
+		grant.NewGrant(
+			resource,
+			primaryContactEntitlement,
+			&v2.ResourceId{
+				ResourceType: resourceTypeIssuerContact.Id,
+				Resource:     o.syncOptions.resourceId(issuerContactId(issuerId, contact.Id)),
+			},
+		),
+	)
+
+	return rv, nil
+}
+
+// boardGrants creates board grants for the issuer's board members. The seats recorded while listing the board
+// members are granted at once. A board that wasn't listed in this process, e.g. when a sync resumes from a
+// checkpoint taken after the listings, is fetched a page at a time.
+func (o *issuerResourceType) boardGrants(ctx context.Context, resource *v2.Resource, after string) ([]*v2.Grant, string, error) {
+	issuerId := o.syncOptions.cartaId(resource.Id.Resource)
+	if seats, ok := o.syncOptions.run.boardSeats().seats(issuerId); ok {
+		return o.seatGrants(resource, seats), "", nil
+	}
+
+	members, nextToken, err := o.client.GetBoardMembersForIssuer(
+		ctx,
+		issuerId,
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeBoardMember.Id), After: after},
+	)
+	if err != nil {
+		// a board hidden from the access token has no seats to grant, the issuer's other grants are kept
+		if carta.IsAccessDenied(err) {
+			ctxzap.Extract(ctx).Warn(
+				"carta-connector: access token has no visibility of the issuer board, skipping its board grants",
+				zap.String("issuer_id", issuerId),
+				zap.Error(err),
+			)
+
+			return nil, "", nil
+		}
+
+		return nil, "", fmt.Errorf("carta-connector: failed to list issuer board members: %w", err)
+	}
+
+	// members point at the board member resource as it was first listed, whatever issuer listed it
+	seats := make([]string, 0, len(members))
+	for _, member := range members {
+		seats = append(seats, o.syncOptions.listedIds.resolve(resourceTypeBoardMember.Id, member.Id))
+	}
+
+	return o.seatGrants(resource, uniqueIds(seats)), nextToken, nil
 }
 
-func issuerBuilder(client *carta.Client) *issuerResourceType {
+// seatGrants returns the board grants of the issuer to its board members.
+func (o *issuerResourceType) seatGrants(resource *v2.Resource, memberIds []string) []*v2.Grant {
+	rv := make([]*v2.Grant, 0, len(memberIds))
+	for _, memberId := range memberIds {
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				boardEntitlement,
+				&v2.ResourceId{
+					ResourceType: resourceTypeBoardMember.Id,
+					Resource:     o.syncOptions.resourceId(memberId),
+				},
+			),
+		)
+	}
+
+	return rv
+}
+
+// parentResourceId returns the parent company of a subsidiary issuer as its parent resource, unless the
+// issuer is listed under another resource or its parent company would close a cycle.
+func (o *issuerResourceType) parentResourceId(ctx context.Context, issuer *carta.Issuer, parentId *v2.ResourceId) *v2.ResourceId {
+	if parentId != nil || issuer.ParentCompanyId == "" {
+		return parentId
+	}
+
+	if !o.syncOptions.run.issuerHierarchy(o.resourceType.Id).link(issuer.Id, issuer.ParentCompanyId) {
+		ctxzap.Extract(ctx).Warn(
+			"carta-connector: issuer parent company forms a cycle, syncing the issuer without parent",
+			zap.String("issuer_id", issuer.Id),
+			zap.String("parent_company_id", issuer.ParentCompanyId),
+		)
+
+		return nil
+	}
+
+	return &v2.ResourceId{
+		ResourceType: o.resourceType.Id,
+		Resource:     o.syncOptions.resourceId(issuer.ParentCompanyId),
+	}
+}
+
+func issuerBuilder(client *carta.Client, syncOptions syncOptions) *issuerResourceType {
 	return &issuerResourceType{
 		resourceType: resourceTypeIssuer,
 		client:       client,
+		syncOptions:  syncOptions,
+		pageSizer:    newPageSizer(syncOptions.adaptivePageSize, syncOptions.pageSize(resourceTypeIssuer.Id)),
+	}
+}
+
+// fundBuilder syncs fund issuers, it lists all issuers and keeps only the funds.
+func fundBuilder(client *carta.Client, syncOptions syncOptions) *issuerResourceType {
+	return &issuerResourceType{
+		resourceType: resourceTypeFund,
+		client:       client,
+		syncOptions:  syncOptions,
+		pageSizer:    newPageSizer(syncOptions.adaptivePageSize, syncOptions.pageSize(resourceTypeFund.Id)),
 	}
 }
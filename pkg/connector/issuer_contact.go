@@ -0,0 +1,89 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type issuerContactResourceType struct {
+	resourceType *v2.ResourceType
+	client       *carta.Client
+	syncOptions  syncOptions
+}
+
+func (o *issuerContactResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for the primary admin contact of a Carta Issuer.
+func issuerContactResource(ctx context.Context, so syncOptions, issuerId string, contact *carta.IssuerContact, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"login":      contact.Email,
+		"email":      contact.Email,
+		"contact_id": contact.Id,
+	}
+
+	return newUserResource(
+		so.displayName(resourceTypeIssuerContact, displayNameData{Name: contact.Name, LegalName: contact.Name, Id: contact.Id}),
+		so.resourceId(issuerContactId(issuerId, contact.Id)),
+		resourceTypeIssuerContact,
+		profile,
+		v2.UserTrait_Status_STATUS_UNSPECIFIED,
+		parentResourceID,
+	)
+}
+
+func (o *issuerContactResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// contacts are only listed under their issuer
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	issuerId := o.syncOptions.cartaId(parentId.Resource)
+	contact, err := o.client.GetIssuerContact(ctx, issuerId)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("carta-connector: failed to get issuer contact: %w", err)
+	}
+
+	if contact == nil {
+		return nil, "", nil, nil
+	}
+
+	cr, err := issuerContactResource(ctx, o.syncOptions, issuerId, contact, parentId)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if err := o.syncOptions.countResources(resourceTypeIssuerContact.Id, 1); err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Resource{cr}, "", nil, nil
+}
+
+func (o *issuerContactResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *issuerContactResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// issuerContactId scopes a contact id to its issuer, as the same person may be the primary contact of
+// several issuers while a resource has a single parent.
+func issuerContactId(issuerId string, contactId string) string {
+	return issuerId + "/" + contactId
+}
+
+func issuerContactBuilder(client *carta.Client, syncOptions syncOptions) *issuerContactResourceType {
+	return &issuerContactResourceType{
+		resourceType: resourceTypeIssuerContact,
+		client:       client,
+		syncOptions:  syncOptions,
+	}
+}
@@ -0,0 +1,138 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type issuerDocumentResourceType struct {
+	resourceType *v2.ResourceType
+	client       *carta.Client
+	syncOptions  syncOptions
+}
+
+func (o *issuerDocumentResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for a document (board consent, filing) of a Carta Issuer.
+// The app trait is the only non identity trait carrying a profile.
+func issuerDocumentResource(ctx context.Context, so syncOptions, document *carta.IssuerDocument, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"document_id":   document.Id,
+		"document_name": document.Name,
+	}
+
+	if document.Type != "" {
+		profile["document_type"] = document.Type
+	}
+
+	if document.Date != "" {
+		documentDate, err := normalizeDate(document.Date)
+		if err != nil {
+			ctxzap.Extract(ctx).Debug(
+				"carta-connector: omitting unparseable document date",
+				zap.String("document_id", document.Id),
+				zap.Error(err),
+			)
+		} else {
+			profile["document_date"] = documentDate
+		}
+	}
+
+	name := document.Name
+	if name == "" {
+		name = document.Id
+	}
+
+	resource, err := rs.NewResource(
+		so.displayName(resourceTypeIssuerDocument, displayNameData{Name: name, LegalName: name, Id: document.Id}),
+		resourceTypeIssuerDocument,
+		so.resourceId(document.Id),
+		rs.WithAppTrait(rs.WithAppProfile(profile)),
+		rs.WithParentResourceID(parentResourceID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+func (o *issuerDocumentResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// documents are only listed under their issuer
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeIssuerDocument.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	documents, nextToken, err := o.client.GetDocumentsForIssuer(
+		ctx,
+		o.syncOptions.cartaId(parentId.Resource),
+		carta.PaginationParams{Size: o.syncOptions.pageSize(resourceTypeIssuerDocument.Id), After: bag.PageToken()},
+	)
+	if err != nil {
+		// the token may not be allowed to see the documents of every issuer
+		if carta.IsAccessDenied(err) {
+			ctxzap.Extract(ctx).Debug(
+				"carta-connector: skipping issuer documents, access denied",
+				zap.String("issuer_id", parentId.Resource),
+			)
+			return nil, "", nil, nil
+		}
+
+		return nil, "", nil, fmt.Errorf("carta-connector: failed to list issuer documents: %w", err)
+	}
+
+	pageToken, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	for _, document := range documents {
+		documentCopy := document
+		dr, err := issuerDocumentResource(ctx, o.syncOptions, &documentCopy, parentId)
+
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, dr)
+	}
+
+	if err := o.syncOptions.countResources(resourceTypeIssuerDocument.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+func (o *issuerDocumentResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *issuerDocumentResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func issuerDocumentBuilder(client *carta.Client, syncOptions syncOptions) *issuerDocumentResourceType {
+	return &issuerDocumentResourceType{
+		resourceType: resourceTypeIssuerDocument,
+		client:       client,
+		syncOptions:  syncOptions,
+	}
+}
@@ -0,0 +1,47 @@
+package connector
+
+import (
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+)
+
+// issuerHierarchy records the parent companies assigned to issuers during a sync, so a subsidiary
+// structure that loops back on itself isn't emitted as a resource tree cycle.
+type issuerHierarchy struct {
+	mtx     sync.Mutex
+	parents map[string]string
+}
+
+func newIssuerHierarchy() *issuerHierarchy {
+	return &issuerHierarchy{
+		parents: make(map[string]string),
+	}
+}
+
+// link records parentId as the parent company of issuerId, it returns false and records nothing
+// when the parent is the issuer itself or one of its known subsidiaries.
+func (h *issuerHierarchy) link(issuerId string, parentId string) bool {
+	issuerId = carta.NormalizeId(issuerId)
+	parentId = carta.NormalizeId(parentId)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	// walk up from the parent, every issuer is visited at most once so a recorded cycle can't loop forever
+	visited := make(map[string]struct{})
+	for ancestor := parentId; ancestor != ""; ancestor = h.parents[ancestor] {
+		if ancestor == issuerId {
+			return false
+		}
+
+		if _, ok := visited[ancestor]; ok {
+			break
+		}
+		visited[ancestor] = struct{}{}
+	}
+
+	h.parents[issuerId] = parentId
+
+	return true
+}
@@ -0,0 +1,446 @@
+package connector
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLazyIssuersListPortfolioMembers(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.issuers = append(f.issuers, fakeIssuer("umbrella", "Umbrella"))
+	// acme is a member of both portfolios and must be listed once
+	f.portfolios[1].members = append(f.portfolios[1].members, fakeIssuer("acme", "Acme Corp"))
+
+	result := runSync(t, f.connector(t, WithLazyIssuers(true)))
+	assertSyncInvariants(t, result)
+
+	for _, id := range []string{"acme", "globex", "initech"} {
+		if !result.hasResource(resourceTypeIssuer, id) {
+			t.Errorf("portfolio member %s was not synced", id)
+		}
+	}
+
+	if result.hasResource(resourceTypeIssuer, "umbrella") {
+		t.Error("issuer umbrella is no portfolio member but was synced")
+	}
+
+	// the grants reference the stored issuers, and their children are synced under them
+	for _, portfolioId := range []string{"growth", "seed"} {
+		if !result.hasGrant(resourceTypePortfolio, portfolioId, memberEntitlement, resourceTypeIssuer, "acme") {
+			t.Errorf("acme has no member grant on portfolio %s", portfolioId)
+		}
+	}
+
+	if !result.hasGrant(resourceTypeIssuer, "acme", primaryContactEntitlement, resourceTypeIssuerContact, issuerContactId("acme", "alice")) {
+		t.Error("the contact of a lazily listed issuer was not granted")
+	}
+
+	for _, id := range []string{"bob", "carol"} {
+		if !result.hasGrant(resourceTypeIssuer, "acme", boardEntitlement, resourceTypeBoardMember, id) {
+			t.Errorf("board member %s of a lazily listed issuer has no board grant", id)
+		}
+	}
+}
+
+func TestLazyIssuersSkipMissingMembers(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.portfolios[1].members = append(f.portfolios[1].members, carta.Issuer{BaseResource: carta.BaseResource{Id: "gone"}})
+
+	result := runSync(t, f.connector(t, WithLazyIssuers(true)))
+	assertSyncInvariants(t, result)
+
+	if result.hasResource(resourceTypeIssuer, "gone") || result.hasGrant(resourceTypePortfolio, "seed", memberEntitlement, resourceTypeIssuer, "gone") {
+		t.Error("the member that no longer exists was synced")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "seed", memberEntitlement, resourceTypeIssuer, "initech") {
+		t.Error("initech has no member grant on portfolio seed")
+	}
+}
+
+func TestLazyIssuersGrantOnlySyncedResources(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.issuers = append(f.issuers, fakeIssuer("umbrella", "Umbrella"))
+	f.issuers[0].ManagingFirmId = "sequoia"
+	// kleiner manages globex without being visible to the access token
+	f.issuers[1].ManagingFirmId = "kleiner"
+	f.firmIssuers["sequoia"] = []carta.Issuer{fakeIssuer("acme", "Acme Corp"), fakeIssuer("umbrella", "Umbrella")}
+
+	result := runSync(t, f.connector(t, WithLazyIssuers(true)))
+	assertSyncInvariants(t, result)
+
+	if !result.hasGrant(resourceTypeInvestor, "sequoia", investmentEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the investment in portfolio member acme was not granted")
+	}
+
+	if result.hasGrant(resourceTypeInvestor, "sequoia", investmentEntitlement, resourceTypeIssuer, "umbrella") {
+		t.Error("the investment in umbrella, which lazy mode doesn't list, was granted")
+	}
+
+	if !result.hasGrant(resourceTypeInvestor, "sequoia", administersEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the listed managing firm of acme has no administers grant")
+	}
+
+	if result.hasGrant(resourceTypeInvestor, "kleiner", administersEntitlement, resourceTypeIssuer, "globex") {
+		t.Error("the managing firm of globex, which isn't listed, has an administers grant")
+	}
+}
+
+func TestBoardMemberOnSeveralBoards(t *testing.T) {
+	f := newFixtureCarta(t)
+	carol := f.boardMembers["acme"][1]
+	f.boardMembers["globex"] = []carta.BoardMember{carol}
+	f.boardMembers["initech"] = []carta.BoardMember{carol}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for _, issuerId := range []string{"acme", "globex", "initech"} {
+		if !result.hasGrant(resourceTypeIssuer, issuerId, boardEntitlement, resourceTypeBoardMember, "carol") {
+			t.Errorf("board member carol has no board grant on %s", issuerId)
+		}
+	}
+
+	if !result.hasGrant(resourceTypeIssuer, "acme", boardEntitlement, resourceTypeBoardMember, "bob") {
+		t.Error("board member bob has no board grant on acme")
+	}
+
+	if result.hasGrant(resourceTypeIssuer, "globex", boardEntitlement, resourceTypeBoardMember, "bob") {
+		t.Error("board member bob has a board grant on globex, whose board bob doesn't sit on")
+	}
+}
+
+func TestBoardGrantsFromListedSeats(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.boardMembers["globex"] = []carta.BoardMember{f.boardMembers["acme"][1]}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for issuerId, members := range map[string][]string{"acme": {"bob", "carol"}, "globex": {"carol"}} {
+		for _, memberId := range members {
+			if !result.hasGrant(resourceTypeIssuer, issuerId, boardEntitlement, resourceTypeBoardMember, memberId) {
+				t.Errorf("board member %s has no board grant on %s", memberId, issuerId)
+			}
+		}
+
+		// the board listing and the board grants share a single fetch
+		if count := f.requestCount("issuers/" + issuerId + "/board-members"); count != 1 {
+			t.Errorf("the board of %s was fetched %d times, want 1", issuerId, count)
+		}
+	}
+}
+
+func TestBoardAccessDenied(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.failures["issuers/acme/board-members"] = http.StatusForbidden
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for _, id := range []string{"bob", "carol"} {
+		if result.hasResource(resourceTypeBoardMember, id) || result.hasGrant(resourceTypeIssuer, "acme", boardEntitlement, resourceTypeBoardMember, id) {
+			t.Errorf("board member %s of the hidden board was synced", id)
+		}
+	}
+
+	if !result.hasGrant(resourceTypeIssuer, "acme", primaryContactEntitlement, resourceTypeIssuerContact, issuerContactId("acme", "alice")) {
+		t.Error("acme lost its other grants with its board hidden")
+	}
+}
+
+func TestBoardGrantsFetchBoardsNotListed(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.failures["issuers/globex/board-members"] = http.StatusForbidden
+	ctx := testContext(t)
+
+	// a sync resumed after the listings grants boards the process never listed
+	issuers := resourceSyncer(t, f.connector(t), resourceTypeIssuer)
+	resources, _, _, err := issuers.List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	for _, resource := range resources {
+		var members []string
+		token := ""
+		for {
+			grants, next, _, err := issuers.Grants(ctx, resource, &pagination.Token{Token: token})
+			if err != nil {
+				t.Fatalf("Grants() of %s error = %v", resource.Id.Resource, err)
+			}
+
+			for _, g := range grants {
+				if g.Principal.Id.ResourceType == resourceTypeBoardMember.Id {
+					members = append(members, g.Principal.Id.Resource)
+				}
+			}
+
+			if token = next; token == "" {
+				break
+			}
+		}
+
+		want := map[string][]string{"acme": {"bob", "carol"}}[resource.Id.Resource]
+		if !reflect.DeepEqual(members, want) {
+			t.Errorf("board grants of %s went to %v, want %v", resource.Id.Resource, members, want)
+		}
+	}
+}
+
+func TestIssuerContactSharedByIssuers(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.contacts["globex"] = f.contacts["acme"]
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for _, issuerId := range []string{"acme", "globex"} {
+		contactId := issuerContactId(issuerId, "alice")
+
+		contact, ok := result.resources[resourceKey(&v2.ResourceId{ResourceType: resourceTypeIssuerContact.Id, Resource: contactId})]
+		if !ok {
+			t.Fatalf("the contact of %s was not synced", issuerId)
+		}
+
+		if parent := contact.ParentResourceId; parent == nil || parent.Resource != issuerId {
+			t.Errorf("the contact of %s has parent %v", issuerId, parent)
+		}
+
+		if !result.hasGrant(resourceTypeIssuer, issuerId, primaryContactEntitlement, resourceTypeIssuerContact, contactId) {
+			t.Errorf("the contact of %s has no primary contact grant", issuerId)
+		}
+
+		// the contact listing and the issuer grants share a single fetch
+		if count := f.requestCount("issuers/" + issuerId + "/contact"); count != 1 {
+			t.Errorf("the contact of %s was fetched %d times, want 1", issuerId, count)
+		}
+	}
+
+	// issuers without contact are fetched once as well
+	if count := f.requestCount("issuers/initech/contact"); count != 1 {
+		t.Errorf("the missing contact of initech was fetched %d times, want 1", count)
+	}
+
+	if result.hasGrant(resourceTypeIssuer, "initech", primaryContactEntitlement, resourceTypeIssuerContact, issuerContactId("initech", "alice")) {
+		t.Error("initech without contact has a primary contact grant")
+	}
+}
+
+func TestLargeStakeholderCountWarning(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.issuers[0].StakeholderCount = 5000
+	f.issuers[1].StakeholderCount = 100
+
+	result := runSync(t, f.connector(t, WithStakeholderWarningThreshold(1000)))
+	assertSyncInvariants(t, result)
+
+	var warned []string
+	for _, annotation := range result.client.listAnnotations {
+		fields := annotation.GetFields()
+		if fields["warning"].GetStringValue() != "large_stakeholder_count" {
+			continue
+		}
+
+		warned = append(warned, fields["issuer_id"].GetStringValue())
+
+		if count := fields["stakeholder_count"].GetNumberValue(); count != 5000 {
+			t.Errorf("warning stakeholder count = %v, want 5000", count)
+		}
+
+		if threshold := fields["threshold"].GetNumberValue(); threshold != 1000 {
+			t.Errorf("warning threshold = %v, want 1000", threshold)
+		}
+	}
+
+	if len(warned) != 1 || warned[0] != "acme" {
+		t.Errorf("stakeholder warnings for %v, want acme only", warned)
+	}
+
+	// the issuer is still synced
+	if !result.hasResource(resourceTypeIssuer, "acme") {
+		t.Error("the issuer with a large stakeholder count was not synced")
+	}
+}
+
+func TestStakeholderWarningDisabled(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.issuers[0].StakeholderCount = 5000
+
+	result := runSync(t, f.connector(t, WithStakeholderWarningThreshold(0)))
+
+	if len(result.client.listAnnotations) != 0 {
+		t.Errorf("listing returned annotations %v without a stakeholder warning threshold", result.client.listAnnotations)
+	}
+}
+
+func TestUnchangedIssuerProfileIsStable(t *testing.T) {
+	f := newFixtureCarta(t)
+	setAcme := func(f *fakeCarta, acme carta.Issuer) {
+		f.issuers[0] = acme
+		f.portfolios[0].members[0] = acme
+	}
+
+	acme := f.issuers[0]
+	acme.AlternateNames = []string{"Acme", "ACME Holdings", "Roadrunner Traps"}
+	acme.Website = "https://acme.test"
+	acme.State = "CA"
+	acme.Country = "US"
+	acme.IncorporationDate = "2015-03-01"
+	acme.Last409AValue = "1.25"
+	acme.PostMoneyValuation = "120000000"
+	setAcme(f, acme)
+
+	cartaConnector := f.connector(t)
+	first := runSync(t, cartaConnector)
+	assertSyncInvariants(t, first)
+
+	// the same issuer, with its alternate names reordered and whitespace around its fields
+	f.update(func(f *fakeCarta) {
+		acme.AlternateNames = []string{" Roadrunner Traps", "ACME Holdings ", "Acme"}
+		acme.Website = " https://acme.test "
+		acme.State = "CA "
+		acme.Type = " company"
+		setAcme(f, acme)
+	})
+
+	second := runSync(t, cartaConnector)
+	assertSyncInvariants(t, second)
+
+	firstProfile := issuerProfileBytes(t, first)
+	secondProfile := issuerProfileBytes(t, second)
+	if !bytes.Equal(firstProfile, secondProfile) {
+		t.Errorf("profile of the unchanged issuer differs between syncs:\n%s\n%s", firstProfile, secondProfile)
+	}
+}
+
+// issuerProfileBytes returns the deterministically serialized profile of the stored acme issuer.
+func issuerProfileBytes(t *testing.T, result *syncResult) []byte {
+	t.Helper()
+
+	resource, ok := result.resources[resourceKey(&v2.ResourceId{ResourceType: resourceTypeIssuer.Id, Resource: "acme"})]
+	if !ok {
+		t.Fatal("issuer acme was not synced")
+	}
+
+	trait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		t.Fatalf("failed to read the user trait of acme: %v", err)
+	}
+
+	profile, err := proto.MarshalOptions{Deterministic: true}.Marshal(trait.Profile)
+	if err != nil {
+		t.Fatalf("failed to serialize the profile of acme: %v", err)
+	}
+
+	return profile
+}
+
+func TestGrantPrincipalsResolveAcrossIdCasing(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.issuers[0].ManagingFirmId = "SEQUOIA"
+	f.firmIssuers["sequoia"] = []carta.Issuer{fakeIssuer("ACME", "Acme Corp")}
+	// globex lists carol in another casing, whichever board lists her first is the one synced
+	f.boardMembers["globex"] = []carta.BoardMember{{BaseResource: carta.BaseResource{Id: "CAROL"}, Name: "Carol"}}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	if !result.hasGrant(resourceTypeInvestor, "sequoia", administersEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the managing firm listed in another casing has no administers grant on the synced firm")
+	}
+
+	if !result.hasGrant(resourceTypeInvestor, "sequoia", investmentEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the issuer the firm lists in another casing has no investment grant to the synced issuer")
+	}
+
+	carolId := "carol"
+	if !result.hasResource(resourceTypeBoardMember, carolId) {
+		carolId = "CAROL"
+	}
+	if result.hasResource(resourceTypeBoardMember, "carol") == result.hasResource(resourceTypeBoardMember, "CAROL") {
+		t.Fatal("board member carol was not synced exactly once")
+	}
+
+	for _, issuerId := range []string{"acme", "globex"} {
+		if !result.hasGrant(resourceTypeIssuer, issuerId, boardEntitlement, resourceTypeBoardMember, carolId) {
+			t.Errorf("board member carol has no board grant on %s to the synced member %s", issuerId, carolId)
+		}
+	}
+}
+
+func TestDuplicateIssuerAcrossPagesListedOnce(t *testing.T) {
+	f := newFixtureCarta(t)
+	// the fixture pages issuers by two, acme is listed again on the second page in another casing
+	f.issuers = []carta.Issuer{fakeIssuer("acme", "Acme Corp"), fakeIssuer("globex", "Globex"), fakeIssuer("ACME", "Acme Corp"), fakeIssuer("initech", "Initech")}
+
+	pages := listedPages(t, resourceSyncer(t, f.connector(t), resourceTypeIssuer))
+
+	want := [][]string{
+		{"acme", "globex"},
+		// listing the first page again isn't a duplicate of its first listing
+		{"acme", "globex"},
+		{"initech"},
+	}
+	// the pages that follow walk the portfolio members, which are all listed already
+	if len(pages) < len(want) || !reflect.DeepEqual(pages[:len(want)], want) {
+		t.Errorf("List() pages = %v, want them to start with %v", pages, want)
+	}
+
+	for _, page := range pages[len(want):] {
+		if len(page) != 0 {
+			t.Errorf("the portfolio members listed issuers %v again", page)
+		}
+	}
+}
+
+func TestIssuerJurisdictionProfile(t *testing.T) {
+	ctx := testContext(t)
+	for _, tc := range []struct {
+		name        string
+		country     string
+		state       string
+		wantCountry string
+		wantState   string
+	}{
+		{name: "alpha-2", country: "US", state: "DE", wantCountry: "US", wantState: "DE"},
+		{name: "alpha-3", country: "gbr", wantCountry: "GB"},
+		{name: "numeric", country: "276", state: " Berlin ", wantCountry: "DE", wantState: "Berlin"},
+		{name: "unrecognised", country: "Atlantis", wantCountry: "Atlantis"},
+		{name: "absent"},
+		{name: "blank", country: "  ", state: "  "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			issuer := fakeIssuer("acme", "Acme Corp")
+			issuer.Country = tc.country
+			issuer.State = tc.state
+
+			resource, err := issuerResource(ctx, syncOptions{}, &issuer, resourceTypeIssuer, nil)
+			if err != nil {
+				t.Fatalf("issuerResource() error = %v", err)
+			}
+
+			trait, err := rs.GetUserTrait(resource)
+			if err != nil {
+				t.Fatalf("issuer resource has no user trait: %v", err)
+			}
+
+			for key, want := range map[string]string{"issuer_country": tc.wantCountry, "issuer_state": tc.wantState} {
+				got, ok := rs.GetProfileStringValue(trait.Profile, key)
+				if want == "" && ok {
+					t.Errorf("profile %s = %q, want it omitted", key, got)
+				} else if want != "" && got != want {
+					t.Errorf("profile %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package connector
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	minAdaptivePageSize  = 10
+	maxAdaptivePageSize  = 250
+	fastPageLatency      = time.Second
+	slowPageLatency      = 5 * time.Second
+	adaptivePageIncrease = 1.5
+)
+
+// pageSizer picks the page size of List calls. In adaptive mode it grows the page size while pages
+// are fast and shrinks it on slow or failed pages, within fixed bounds.
+type pageSizer struct {
+	mtx      sync.Mutex
+	adaptive bool
+	size     int
+}
+
+// newPageSizer returns a page sizer starting from the given page size.
+func newPageSizer(adaptive bool, size int) *pageSizer {
+	return &pageSizer{
+		adaptive: adaptive,
+		size:     size,
+	}
+}
+
+func (ps *pageSizer) current() int {
+	if !ps.adaptive {
+		return ps.size
+	}
+
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	return ps.size
+}
+
+// observe adjusts the page size based on the latency and outcome of a page fetch.
+func (ps *pageSizer) observe(latency time.Duration, err error) {
+	if !ps.adaptive {
+		return
+	}
+
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	switch {
+	case err != nil || latency > slowPageLatency:
+		ps.size /= 2
+	case latency < fastPageLatency:
+		ps.size = int(float64(ps.size) * adaptivePageIncrease)
+	}
+
+	if ps.size < minAdaptivePageSize {
+		ps.size = minAdaptivePageSize
+	}
+
+	if ps.size > maxAdaptivePageSize {
+		ps.size = maxAdaptivePageSize
+	}
+}
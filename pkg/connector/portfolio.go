@@ -2,8 +2,10 @@ package connector
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ConductorOne/baton-carta/pkg/carta"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -12,13 +14,21 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const memberEntitlement = "member"
 
+// viewerEntitlement is granted to issuers with read-only access to a portfolio.
+const viewerEntitlement = "viewer"
+
 type portfolioResourceType struct {
 	resourceType *v2.ResourceType
 	client       *carta.Client
+	syncOptions  syncOptions
+	pageSizer    *pageSizer
 }
 
 func (o *portfolioResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -26,11 +36,56 @@ func (o *portfolioResourceType) ResourceType(_ context.Context) *v2.ResourceType
 }
 
 // Create a new connector resource for an Carta Portfolio (Grouping entity of issuers).
-func portfolioResource(ctx context.Context, portfolio *carta.Portfolio, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+func portfolioResource(ctx context.Context, so syncOptions, portfolio *carta.Portfolio, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"portfolio_legal_name": portfolio.Name,
 		"portfolio_id":         portfolio.Id,
-		"portfolio_issuer_ids": strings.Join(mapIssuerIds(portfolio.Issuers), ","),
+	}
+
+	// investor firms the portfolio is shared with
+	if len(portfolio.Firms) > 0 {
+		firmIds := make([]string, 0, len(portfolio.Firms))
+		for _, firm := range portfolio.Firms {
+			firmIds = append(firmIds, firm.Id)
+		}
+
+		profile["portfolio_investor_firm_ids"] = strings.Join(sortedUniqueIds(firmIds), ",")
+	}
+
+	// audit fields are only set when Carta returns them
+	for key, raw := range map[string]string{
+		"portfolio_created_at": portfolio.CreatedAt,
+		"portfolio_updated_at": portfolio.UpdatedAt,
+	} {
+		if raw == "" {
+			continue
+		}
+
+		timestamp, err := normalizeDate(raw)
+		if err != nil {
+			ctxzap.Extract(ctx).Debug(
+				"carta-connector: omitting unparseable portfolio timestamp",
+				zap.String("portfolio_id", portfolio.Id),
+				zap.String("field", key),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		profile[key] = timestamp
+	}
+
+	if createdBy := strings.TrimSpace(portfolio.CreatedBy); createdBy != "" {
+		profile["portfolio_created_by"] = createdBy
+	}
+
+	// nested portfolios point to their parent portfolio
+	if portfolio.ParentId != "" && carta.NormalizeId(portfolio.ParentId) != carta.NormalizeId(portfolio.Id) {
+		profile["portfolio_parent_id"] = portfolio.ParentId
+		parentResourceID = &v2.ResourceId{
+			ResourceType: resourceTypePortfolio.Id,
+			Resource:     so.resourceId(portfolio.ParentId),
+		}
 	}
 
 	portfolioTraitOptions := []rs.GroupTraitOption{
@@ -38,10 +93,15 @@ func portfolioResource(ctx context.Context, portfolio *carta.Portfolio, parentRe
 	}
 
 	resource, err := rs.NewGroupResource(
-		portfolio.Name,
+		so.displayName(resourceTypePortfolio, displayNameData{
+			Name:      resourceDisplayName(portfolio.DisplayName, portfolio.Name),
+			LegalName: portfolio.Name,
+			Id:        portfolio.Id,
+		}),
 		resourceTypePortfolio,
-		portfolio.Id,
+		so.resourceId(portfolio.Id),
 		portfolioTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
 	)
 
 	if err != nil {
@@ -52,17 +112,21 @@ func portfolioResource(ctx context.Context, portfolio *carta.Portfolio, parentRe
 }
 
 func (o *portfolioResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypePortfolio.Id})
+	bag, err := o.syncOptions.listPageToken(ctx, token.Token, resourceTypePortfolio.Id)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	after := bag.PageToken()
+
+	start := time.Now()
 	portfolios, nextToken, err := o.client.GetPortfolios(
 		ctx,
-		carta.PaginationParams{Size: ResourcesPageSize, After: bag.PageToken()},
+		carta.PaginationParams{Size: o.pageSizer.current(), After: after, NamePrefix: o.syncOptions.portfolioNamePrefix},
 	)
+	o.pageSizer.observe(time.Since(start), err)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("carta-connector: failed to list portfolios: %w", err)
+		return nil, "", nil, o.syncOptions.pageError(ctx, resourceTypePortfolio.Id, after, fmt.Errorf("carta-connector: failed to list portfolios: %w", err))
 	}
 
 	pageToken, err := bag.NextToken(nextToken)
@@ -70,25 +134,71 @@ func (o *portfolioResourceType) List(ctx context.Context, parentId *v2.ResourceI
 		return nil, "", nil, err
 	}
 
+	isDuplicate := o.syncOptions.run.duplicateDetector(resourceTypePortfolio.Id).page(after)
+
 	var rv []*v2.Resource
 	for _, portfolio := range portfolios {
+		// don't rely on Carta applying the prefix filter
+		if !hasNamePrefix(portfolio, o.syncOptions.portfolioNamePrefix) {
+			continue
+		}
+
+		if isDuplicate(ctx, resourceTypePortfolio.Id, portfolio.Id) {
+			continue
+		}
+
+		firms, err := o.client.GetAllInvestorsForPortfolio(ctx, portfolio.Id)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("carta-connector: failed to list firms of portfolio %s: %w", portfolio.Id, err)
+		}
+
+		portfolio.Firms = firms
+
+		if o.syncOptions.skipEmptyPortfolios {
+			empty, err := o.isEmptyPortfolio(ctx, portfolio)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			if empty {
+				continue
+			}
+		}
+
+		// firm contacts are granted the portfolio while their firm can access it
+		o.syncOptions.run.portfolioAccess().share(portfolio.Id, portfolio.Firms)
+
+		// issuers granted through a sub-portfolio are not granted again on its parent, the parent's grants
+		// are only listed once every portfolio page was
+		if portfolio.ParentId != "" && carta.NormalizeId(portfolio.ParentId) != carta.NormalizeId(portfolio.Id) {
+			o.syncOptions.run.portfolioHierarchy().addChild(portfolio.ParentId, portfolio.Id)
+		}
+
 		portfolioCopy := portfolio
-		pr, err := portfolioResource(ctx, &portfolioCopy, parentId)
+		pr, err := portfolioResource(ctx, o.syncOptions, &portfolioCopy, parentId)
 
 		if err != nil {
 			return nil, "", nil, err
 		}
 
+		o.syncOptions.listedIds.add(resourceTypePortfolio.Id, portfolio.Id)
 		rv = append(rv, pr)
 	}
 
+	if err := o.syncOptions.countResources(resourceTypePortfolio.Id, len(rv)); err != nil {
+		return nil, "", nil, err
+	}
+
 	return rv, pageToken, nil, nil
 }
 
 func (o *portfolioResourceType) Entitlements(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
+	// portfolio access can be held by issuers as well as investor firms and their contacts
+	grantableTo := append(o.syncOptions.issuerResourceTypes(), resourceTypeInvestor, resourceTypeInvestorContact)
+
 	assignmentOptions := []ent.EntitlementOption{
-		ent.WithGrantableTo(resourceTypeIssuer),
+		ent.WithGrantableTo(grantableTo...),
 		ent.WithDisplayName(fmt.Sprintf("%s Portfolio %s", resource.DisplayName, memberEntitlement)),
 		ent.WithDescription(fmt.Sprintf("Access to %s portfolio in Carta", resource.DisplayName)),
 	}
@@ -100,52 +210,439 @@ func (o *portfolioResourceType) Entitlements(ctx context.Context, resource *v2.R
 		assignmentOptions...,
 	))
 
-	return rv, "", nil, nil
+	viewerOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(grantableTo...),
+		ent.WithDisplayName(fmt.Sprintf("%s Portfolio %s", resource.DisplayName, viewerEntitlement)),
+		ent.WithDescription(fmt.Sprintf("Read-only access to %s portfolio in Carta", resource.DisplayName)),
+	}
+
+	// create read-only entitlement
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		viewerEntitlement,
+		viewerOptions...,
+	))
+
+	page, nextToken, err := paginate(rv, token.Token, token.Size)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return page, nextToken, nil, nil
 }
 
 func (o *portfolioResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	portfolioTrait, err := rs.GetGroupTrait(resource)
+	if err := o.syncOptions.ensureListed(ctx); err != nil {
+		return nil, "", nil, err
+	}
+
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(token.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	issuerIdsString, ok := rs.GetProfileStringValue(portfolioTrait.Profile, "portfolio_issuer_ids")
-	if !ok {
-		return nil, "", nil, fmt.Errorf("error fetching issuer ids from portfolio profile")
+	// walk the portfolio's member pages first, then grant the firms the portfolio is shared with. The firm
+	// state's token records the firms granted as members, which aren't granted again.
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeInvestor.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeIssuer.Id})
 	}
 
-	issuerIds := strings.Split(issuerIdsString, ",")
+	switch bag.ResourceTypeID() {
+	case resourceTypeIssuer.Id:
+		return o.memberGrantsPage(ctx, resource, bag)
+	case resourceTypeInvestor.Id:
+		rv, err := o.firmGrants(resource, bag.PageToken())
+		if err != nil {
+			return nil, "", nil, err
+		}
 
-	// create membership grants
-	var rv []*v2.Grant
-	for _, id := range issuerIds {
-		issuer, err := o.client.GetIssuer(ctx, id)
+		pageToken, err := bag.NextToken("")
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		issuerCopy := issuer
-		ir, err := issuerResource(ctx, &issuerCopy, nil)
+		return rv, pageToken, nil, nil
+	default:
+		return nil, "", nil, fmt.Errorf("carta-connector: unexpected resource type in portfolio grants page token: %s", bag.ResourceTypeID())
+	}
+}
+
+// memberCursor is the page token of the portfolio member walk, it counts the pages walked so the issuer
+// page cap applies across calls.
+type memberCursor struct {
+	After string `json:"after,omitempty"`
+	Pages int    `json:"pages,omitempty"`
+}
+
+// memberGrantsPage returns the grants of the next page of portfolio members, one page per call.
+func (o *portfolioResourceType) memberGrantsPage(ctx context.Context, resource *v2.Resource, bag *pagination.Bag) ([]*v2.Grant, string, annotations.Annotations, error) {
+	portfolioId := o.syncOptions.cartaId(resource.Id.Resource)
+
+	var cursor memberCursor
+	if token := bag.PageToken(); token != "" {
+		if err := json.Unmarshal([]byte(token), &cursor); err != nil {
+			return nil, "", nil, fmt.Errorf("carta-connector: invalid portfolio member page token %q: %w", token, err)
+		}
+	}
+
+	// issuers granted through a sub-portfolio are not granted again on its parent
+	excluded, err := o.subPortfolioIssuers(ctx, portfolioId)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	cursor.Pages++
+	members, nextToken, truncated, err := o.client.GetIssuersPageForPortfolio(ctx, portfolioId, cursor.After, cursor.Pages)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("carta-connector: failed to list issuers of portfolio %s: %w", portfolioId, err)
+	}
+
+	// the member state sits on top of the firm state, which records the firms granted as members so far
+	memberState := bag.Pop()
+	firmState := bag.Pop()
+	if memberState == nil || firmState == nil || firmState.ResourceTypeID != resourceTypeInvestor.Id {
+		return nil, "", nil, fmt.Errorf("carta-connector: portfolio grants page token of %s lost its firm state", portfolioId)
+	}
+
+	// members repeated on later pages yield the same grant, which the sync stores once
+	granted := make(map[string]struct{})
+	firmIds := splitIds(firmState.Token)
+	for _, id := range firmIds {
+		granted[firmGrantKey(id)] = struct{}{}
+	}
+
+	rv, err := o.memberGrants(ctx, resource, members, excluded, granted)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, member := range members {
+		if strings.EqualFold(strings.TrimSpace(member.MemberType), "firm") {
+			firmIds = append(firmIds, carta.NormalizeId(member.Id))
+		}
+	}
+
+	firmState.Token = strings.Join(sortedUniqueIds(firmIds), ",")
+	bag.Push(*firmState)
+	bag.Push(*memberState)
+
+	next := ""
+	if nextToken != "" {
+		cursorToken, err := json.Marshal(memberCursor{After: nextToken, Pages: cursor.Pages})
 		if err != nil {
 			return nil, "", nil, err
 		}
+		next = string(cursorToken)
+	}
+
+	pageToken, err := bag.NextToken(next)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var annos annotations.Annotations
+	if truncated {
+		annos.Update(issuersTruncatedAnnotation())
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+// firmGrants returns the grants of the firms the portfolio is shared with as investor firms, leaving out
+// the firms granted as members already.
+func (o *portfolioResourceType) firmGrants(resource *v2.Resource, grantedFirmIds string) ([]*v2.Grant, error) {
+	portfolioTrait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	firmIdsString, ok := rs.GetProfileStringValue(portfolioTrait.Profile, "portfolio_investor_firm_ids")
+	if !ok {
+		return nil, nil
+	}
+
+	granted := make(map[string]struct{})
+	for _, id := range splitIds(grantedFirmIds) {
+		granted[carta.NormalizeId(id)] = struct{}{}
+	}
+
+	var rv []*v2.Grant
+	for _, id := range splitIds(firmIdsString) {
+		if _, ok := granted[carta.NormalizeId(id)]; ok {
+			continue
+		}
+
+		rv = append(rv, o.firmGrant(resource, id, memberEntitlement))
+	}
+
+	return rv, nil
+}
+
+// memberGrants returns the grants of a page of portfolio members, leaving out the excluded issuers and
+// the members granted already. Members granted are recorded in granted.
+func (o *portfolioResourceType) memberGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+	members []carta.Issuer,
+	excluded map[string]struct{},
+	granted map[string]struct{},
+) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+	var issuers []carta.Issuer
+	for _, member := range members {
+		key := carta.NormalizeId(member.Id)
+		if key == "" {
+			continue
+		}
+
+		// investor firms may be portfolio members as well
+		if strings.EqualFold(strings.TrimSpace(member.MemberType), "firm") {
+			if _, ok := granted[firmGrantKey(member.Id)]; ok {
+				continue
+			}
+			granted[firmGrantKey(member.Id)] = struct{}{}
+
+			rv = append(rv, o.firmGrant(resource, member.Id, accessEntitlement(member)))
+			continue
+		}
+
+		if _, ok := excluded[key]; ok {
+			continue
+		}
+
+		if _, ok := granted[key]; ok {
+			continue
+		}
+		granted[key] = struct{}{}
+
+		issuers = append(issuers, member)
+	}
+
+	// issuers the portfolio listing doesn't name are fetched up front and concurrently, instead of one by one
+	// below. Without the issuer listing every member is, so members the lazy listing skipped are skipped here
+	// too, the issuers it fetched are cached already. With funds split out every member is fetched as well,
+	// the resource type the issuer was synced as comes from its issuer record, not the portfolio listing.
+	var fetchIds []string
+	for _, member := range issuers {
+		if o.needsIssuerRecord(member) {
+			fetchIds = append(fetchIds, member.Id)
+		}
+	}
+
+	if err := o.client.WarmIssuerCache(ctx, fetchIds); err != nil {
+		return nil, err
+	}
+
+	for _, member := range issuers {
+		issuer := member
+		if o.needsIssuerRecord(member) {
+			resolved, ok, err := resolvePortfolioMember(ctx, o.client, o.syncOptions, carta.LogComponentGrants, resource.Id.Resource, member)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			issuer = resolved
+		}
+
+		// the grant points at the issuer as it was listed, whatever casing the portfolio lists it in
+		resourceType := o.syncOptions.issuerResourceType(&issuer)
+		principal := &v2.ResourceId{
+			ResourceType: resourceType.Id,
+			Resource:     o.syncOptions.resourceId(o.syncOptions.listedIds.resolve(resourceType.Id, issuer.Id)),
+		}
+
+		// memberships maintained by Carta can't be revoked
+		source := grantSourceAnnotation(grantSourcePortfolio)
+		if member.SystemManaged {
+			source = systemManagedGrantSourceAnnotation(grantSourcePortfolio)
+		}
 
 		rv = append(
 			rv,
 			grant.NewGrant(
 				resource,
-				memberEntitlement,
-				ir.Id,
+				accessEntitlement(member),
+				principal,
+				grant.WithAnnotation(source),
 			),
 		)
 	}
 
-	return rv, "", nil, nil
+	return rv, nil
+}
+
+// needsIssuerRecord reports whether the grant of a portfolio member is built from the member's issuer record
+// rather than from what the portfolio listing tells of it.
+func (o *portfolioResourceType) needsIssuerRecord(member carta.Issuer) bool {
+	return member.Name == "" || o.syncOptions.lazyIssuers || o.syncOptions.splitFunds
+}
+
+// resolvePortfolioMember fetches the details of a portfolio member issuer, it returns false for members that
+// no longer exist. Other failures that aren't transient fall back to what the portfolio listing tells of the issuer.
+func resolvePortfolioMember(
+	ctx context.Context,
+	client *carta.Client,
+	so syncOptions,
+	logComponent string,
+	portfolioId string,
+	member carta.Issuer,
+) (carta.Issuer, bool, error) {
+	issuer, err := client.GetIssuer(ctx, member.Id)
+	if err == nil {
+		return issuer, true, nil
+	}
+
+	if ctx.Err() != nil {
+		return carta.Issuer{}, false, err
+	}
+
+	// a member that no longer exists is skipped, there is nothing to grant access to
+	if carta.IsNotFound(err) {
+		so.logLevels.Logger(ctx, logComponent).Warn(
+			"carta-connector: portfolio member issuer not found, skipping its membership",
+			zap.String("portfolio_id", portfolioId),
+			zap.String("issuer_id", member.Id),
+		)
+
+		return carta.Issuer{}, false, nil
+	}
+
+	// transient failures fail the page, so it's retried later instead of synced partially
+	if carta.IsTransient(err) {
+		return carta.Issuer{}, false, fmt.Errorf("carta-connector: failed to get portfolio member issuer %s: %w", member.Id, err)
+	}
+
+	// the membership is known from the portfolio, so keep the member with what is known of the issuer
+	so.logLevels.Logger(ctx, logComponent).Warn(
+		"carta-connector: failed to get issuer details, keeping portfolio member with partial issuer data",
+		zap.String("portfolio_id", portfolioId),
+		zap.String("issuer_id", member.Id),
+		zap.Error(err),
+	)
+
+	if member.Name == "" {
+		member = partialIssuer(member.Id)
+	}
+
+	// the issuer type of the portfolio listing may not be the one of the issuer record, so the member only
+	// goes under the fund resource type when it was listed as a fund
+	member.Type = ""
+	if so.listedIds.has(resourceTypeFund.Id, member.Id) {
+		member.Type = "fund"
+	}
+
+	return member, true, nil
+}
+
+// firmGrant returns the grant of the entitlement on the portfolio to an investor firm.
+func (o *portfolioResourceType) firmGrant(resource *v2.Resource, firmId string, entitlement string) *v2.Grant {
+	return grant.NewGrant(
+		resource,
+		entitlement,
+		&v2.ResourceId{
+			ResourceType: resourceTypeInvestor.Id,
+			Resource:     o.syncOptions.resourceId(o.syncOptions.listedIds.resolve(resourceTypeInvestor.Id, firmId)),
+		},
+		grant.WithAnnotation(grantSourceAnnotation(grantSourcePortfolio)),
+	)
+}
+
+// subPortfolioIssuers returns the normalized ids of the issuers of the sub-portfolios of the portfolio.
+func (o *portfolioResourceType) subPortfolioIssuers(ctx context.Context, portfolioId string) (map[string]struct{}, error) {
+	excluded := make(map[string]struct{})
+	for _, childId := range o.syncOptions.run.portfolioHierarchy().childIds(portfolioId) {
+		_, err := o.client.WalkIssuersForPortfolio(ctx, childId, func(members []carta.Issuer) error {
+			for _, member := range members {
+				excluded[carta.NormalizeId(member.Id)] = struct{}{}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("carta-connector: failed to list issuers of sub-portfolio %s: %w", childId, err)
+		}
+	}
+
+	return excluded, nil
+}
+
+// isEmptyPortfolio reports whether the portfolio has no issuers nor firms to grant access to, a
+// single member is fetched to tell.
+func (o *portfolioResourceType) isEmptyPortfolio(ctx context.Context, portfolio carta.Portfolio) (bool, error) {
+	if len(portfolio.Firms) > 0 {
+		return false, nil
+	}
+
+	members, nextToken, err := o.client.GetIssuersForPortfolio(ctx, portfolio.Id, carta.PaginationParams{Size: 1})
+	if err != nil {
+		return false, fmt.Errorf("carta-connector: failed to list issuers of portfolio %s: %w", portfolio.Id, err)
+	}
+
+	return len(members) == 0 && nextToken == "", nil
+}
+
+// accessEntitlement returns the entitlement granted to a portfolio member, members with read-only
+// access get the viewer entitlement instead of membership.
+func accessEntitlement(member carta.Issuer) string {
+	if strings.EqualFold(strings.TrimSpace(member.AccessLevel), viewerEntitlement) {
+		return viewerEntitlement
+	}
+
+	return memberEntitlement
+}
+
+// firmGrantKey keys investor firm members apart from issuers among the members granted.
+func firmGrantKey(firmId string) string {
+	return resourceTypeInvestor.Id + ":" + carta.NormalizeId(firmId)
+}
+
+// splitIds returns the unique ids of a comma separated list.
+func splitIds(ids string) []string {
+	if ids == "" {
+		return nil
+	}
+
+	return uniqueIds(strings.Split(ids, ","))
+}
+
+// issuersTruncatedAnnotation notes that the portfolio's grants leave out the issuers past the issuer page cap.
+func issuersTruncatedAnnotation() *structpb.Struct {
+	return structAnnotation(map[string]*structpb.Value{
+		"portfolio_issuers_truncated": structpb.NewBoolValue(true),
+	})
+}
+
+// hasNamePrefix reports whether the portfolio's legal or display name starts with the prefix, ignoring case.
+func hasNamePrefix(portfolio carta.Portfolio, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+
+	prefix = strings.ToLower(prefix)
+
+	return strings.HasPrefix(strings.ToLower(portfolio.Name), prefix) ||
+		strings.HasPrefix(strings.ToLower(portfolio.DisplayName), prefix)
+}
+
+// partialIssuer returns an issuer holding only the data known without its details, named after its id.
+func partialIssuer(issuerId string) carta.Issuer {
+	return carta.Issuer{
+		BaseResource: carta.BaseResource{Id: issuerId},
+		Name:         issuerId,
+	}
 }
 
-func portfolioBuilder(client *carta.Client) *portfolioResourceType {
+func portfolioBuilder(client *carta.Client, syncOptions syncOptions) *portfolioResourceType {
 	return &portfolioResourceType{
 		resourceType: resourceTypePortfolio,
 		client:       client,
+		syncOptions:  syncOptions,
+		pageSizer:    newPageSizer(syncOptions.adaptivePageSize, syncOptions.pageSize(resourceTypePortfolio.Id)),
 	}
 }
@@ -0,0 +1,47 @@
+package connector
+
+import (
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+)
+
+// portfolioAccess records the investor firms each portfolio listed during a sync is shared with, so firm
+// contacts are only granted the portfolios their firm can access in this sync.
+type portfolioAccess struct {
+	mtx sync.RWMutex
+	// firms maps each portfolio id to the ids of the firms it's shared with, all normalized.
+	firms map[string]map[string]struct{}
+}
+
+func newPortfolioAccess() *portfolioAccess {
+	return &portfolioAccess{
+		firms: make(map[string]map[string]struct{}),
+	}
+}
+
+// share records the firms the portfolio is shared with.
+func (a *portfolioAccess) share(portfolioId string, firms []carta.InvestorFirm) {
+	portfolioId = carta.NormalizeId(portfolioId)
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.firms[portfolioId] == nil {
+		a.firms[portfolioId] = make(map[string]struct{})
+	}
+
+	for _, firm := range firms {
+		a.firms[portfolioId][carta.NormalizeId(firm.Id)] = struct{}{}
+	}
+}
+
+// shared reports whether the portfolio was listed as shared with the firm.
+func (a *portfolioAccess) shared(portfolioId string, firmId string) bool {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	_, ok := a.firms[carta.NormalizeId(portfolioId)][carta.NormalizeId(firmId)]
+
+	return ok
+}
@@ -0,0 +1,48 @@
+package connector
+
+import (
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+)
+
+// portfolioHierarchy records the sub-portfolios listed during a sync, so the issuers granted through a
+// sub-portfolio aren't granted again on its parent, whichever pages the two portfolios are listed on.
+type portfolioHierarchy struct {
+	mtx sync.Mutex
+	// children maps each parent portfolio id, normalized, to the ids of its sub-portfolios keyed by normalized id.
+	children map[string]map[string]string
+}
+
+func newPortfolioHierarchy() *portfolioHierarchy {
+	return &portfolioHierarchy{
+		children: make(map[string]map[string]string),
+	}
+}
+
+// addChild records childId as a sub-portfolio of parentId.
+func (h *portfolioHierarchy) addChild(parentId string, childId string) {
+	parentId = carta.NormalizeId(parentId)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.children[parentId] == nil {
+		h.children[parentId] = make(map[string]string)
+	}
+
+	h.children[parentId][carta.NormalizeId(childId)] = childId
+}
+
+// childIds returns the ids of the sub-portfolios of parentId.
+func (h *portfolioHierarchy) childIds(parentId string) []string {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	var ids []string
+	for _, id := range h.children[carta.NormalizeId(parentId)] {
+		ids = append(ids, id)
+	}
+
+	return sortedUniqueIds(ids)
+}
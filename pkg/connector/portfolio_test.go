@@ -0,0 +1,493 @@
+package connector
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPortfolioMembersResolveAcrossIdCasing(t *testing.T) {
+	f := newFakeCarta(t)
+
+	acme := fakeIssuer("AcMe-1", "Acme Corp")
+	f.issuers = []carta.Issuer{acme}
+	f.contacts["AcMe-1"] = carta.IssuerContact{BaseResource: carta.BaseResource{Id: "alice"}, Name: "Alice", Email: "alice@acme.test"}
+	f.portfolios = []fakePortfolio{{
+		portfolio: carta.Portfolio{Id: "Growth", Name: "Growth"},
+		members:   []carta.Issuer{fakeIssuer("acme-1", "Acme Corp")},
+		firms:     []carta.InvestorFirm{fakeFirm("SEQUOIA", "Sequoia")},
+	}}
+	f.firms = []carta.InvestorFirm{fakeFirm("Sequoia", "Sequoia")}
+	f.firmContacts["Sequoia"] = []carta.InvestorContact{
+		{BaseResource: carta.BaseResource{Id: "erin"}, Name: "Erin", Email: "erin@sequoia.test", PortfolioIds: []string{"GROWTH"}},
+	}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	// ids keep the casing Carta lists them with, in resource ids as well as requests
+	if !result.hasResource(resourceTypeIssuer, "AcMe-1") {
+		t.Fatal("issuer AcMe-1 was not synced under its listed id")
+	}
+
+	if f.requestCount("issuers/AcMe-1/contact") == 0 {
+		t.Error("the issuer contact was not requested with the listed issuer id")
+	}
+
+	if !result.hasGrant(resourceTypeIssuer, "AcMe-1", primaryContactEntitlement, resourceTypeIssuerContact, issuerContactId("AcMe-1", "alice")) {
+		t.Error("the issuer contact was not granted")
+	}
+
+	// members, firms and contact portfolios listed in another casing resolve to the synced resources
+	if !result.hasGrant(resourceTypePortfolio, "Growth", memberEntitlement, resourceTypeIssuer, "AcMe-1") {
+		t.Error("the portfolio member listed in another casing has no member grant")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "Growth", memberEntitlement, resourceTypeInvestor, "Sequoia") {
+		t.Error("the firm the portfolio is shared with has no member grant")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "Growth", memberEntitlement, resourceTypeInvestorContact, investorContactId("Sequoia", "erin")) {
+		t.Error("the firm contact has no member grant")
+	}
+}
+
+func TestPortfolioSharedWithSeveralFirms(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.firms = append(f.firms, fakeFirm("a16z", "Andreessen Horowitz"))
+	f.portfolios[0].firms = []carta.InvestorFirm{fakeFirm("sequoia", "Sequoia"), fakeFirm("a16z", "Andreessen Horowitz")}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for _, firmId := range []string{"sequoia", "a16z"} {
+		if !result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeInvestor, firmId) {
+			t.Errorf("firm %s the portfolio is shared with has no member grant", firmId)
+		}
+	}
+}
+
+func TestPortfolioFirmsAccessDenied(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.failures["portfolios/growth/firms"] = http.StatusForbidden
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	if !result.hasResource(resourceTypePortfolio, "growth") {
+		t.Fatal("portfolio growth was not synced")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("portfolio growth lost its member grants")
+	}
+
+	if result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeInvestor, "sequoia") {
+		t.Error("portfolio growth has a firm grant although its firms can't be listed")
+	}
+}
+
+func TestNestedPortfolioMembersGrantedOnceAcrossPages(t *testing.T) {
+	f := newFixtureCarta(t)
+
+	acme := fakeIssuer("acme", "Acme Corp")
+
+	// the sub-portfolio is listed on the page after its parent
+	f.portfolios = append(f.portfolios, fakePortfolio{
+		portfolio: carta.Portfolio{Id: "growth-us", Name: "Growth US", ParentId: "growth"},
+		members:   []carta.Issuer{acme},
+	})
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	child, ok := result.resources[resourceKey(&v2.ResourceId{ResourceType: resourceTypePortfolio.Id, Resource: "growth-us"})]
+	if !ok {
+		t.Fatal("sub-portfolio growth-us was not synced")
+	}
+
+	if parent := child.ParentResourceId; parent == nil || parent.ResourceType != resourceTypePortfolio.Id || parent.Resource != "growth" {
+		t.Errorf("sub-portfolio parent = %v, want portfolio growth", parent)
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "growth-us", memberEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the sub-portfolio member has no member grant on the sub-portfolio")
+	}
+
+	if result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the sub-portfolio member is granted again on the parent portfolio")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeIssuer, "globex") {
+		t.Error("the parent portfolio lost the member grants of its own issuers")
+	}
+}
+
+func TestSystemManagedMembershipGrantsAreImmutable(t *testing.T) {
+	f := newFixtureCarta(t)
+	acme := fakeIssuer("acme", "Acme Corp")
+	acme.SystemManaged = true
+	f.portfolios[0].members = []carta.Issuer{acme, fakeIssuer("globex", "Globex")}
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	for id, want := range map[string]bool{"acme": true, "globex": false} {
+		g := result.grant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeIssuer, id)
+		if g == nil {
+			t.Fatalf("member %s has no member grant", id)
+		}
+
+		source := grantAnnotation(t, g)
+		if got := source.GetFields()["immutable"].GetBoolValue(); got != want {
+			t.Errorf("member %s grant immutable = %v, want %v", id, got, want)
+		}
+
+		if got := source.GetFields()["grant_source"].GetStringValue(); got != grantSourcePortfolio {
+			t.Errorf("member %s grant source = %q, want %q", id, got, grantSourcePortfolio)
+		}
+	}
+}
+
+// grantAnnotation returns the struct annotation of the grant.
+func grantAnnotation(t *testing.T, g *v2.Grant) *structpb.Struct {
+	t.Helper()
+
+	source := &structpb.Struct{}
+	annos := annotations.Annotations(g.Annotations)
+	ok, err := annos.Pick(source)
+	if err != nil || !ok {
+		t.Fatalf("grant %s has no struct annotation: %v", g.Id, err)
+	}
+
+	return source
+}
+
+func TestPortfolioListingDoesNotWalkMembers(t *testing.T) {
+	f := newFixtureCarta(t)
+	ctx := testContext(t)
+	portfolios := resourceSyncer(t, f.connector(t), resourceTypePortfolio)
+
+	resources, _, _, err := portfolios.List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(resources) != 2 {
+		t.Fatalf("List() returned %d portfolios, want 2", len(resources))
+	}
+
+	for _, path := range []string{"portfolios/growth/issuers", "portfolios/seed/issuers"} {
+		if count := f.requestCount(path); count != 0 {
+			t.Errorf("listing portfolios requested %s %d times, members are only walked for grants", path, count)
+		}
+	}
+}
+
+func TestPortfolioGrantsWalkMemberPages(t *testing.T) {
+	f := newFixtureCarta(t)
+
+	viewer := fakeIssuer("globex", "Globex")
+	viewer.AccessLevel = "Viewer"
+	firm := carta.Issuer{BaseResource: carta.BaseResource{Id: "sequoia"}, MemberType: "firm"}
+	unnamed := carta.Issuer{BaseResource: carta.BaseResource{Id: "initech"}}
+	f.portfolios[0].members = []carta.Issuer{fakeIssuer("acme", "Acme Corp"), viewer, firm, unnamed, fakeIssuer("acme", "Acme Corp")}
+	f.portfolios[0].firms = nil
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	if count := f.requestCount("portfolios/growth/issuers"); count < 3 {
+		t.Errorf("portfolio growth member pages requested %d times, want every page walked", count)
+	}
+
+	for _, want := range []struct {
+		entitlement   string
+		principalType *v2.ResourceType
+		principalId   string
+	}{
+		{memberEntitlement, resourceTypeIssuer, "acme"},
+		{viewerEntitlement, resourceTypeIssuer, "globex"},
+		{memberEntitlement, resourceTypeInvestor, "sequoia"},
+		{memberEntitlement, resourceTypeIssuer, "initech"},
+	} {
+		if !result.hasGrant(resourceTypePortfolio, "growth", want.entitlement, want.principalType, want.principalId) {
+			t.Errorf("%s %s has no %s grant on portfolio growth", want.principalType.Id, want.principalId, want.entitlement)
+		}
+	}
+
+	// the member listed twice is granted once
+	var acmeGrants int
+	for _, g := range result.grants {
+		if g.Entitlement.Resource.Id.Resource == "growth" && g.Principal.Id.Resource == "acme" {
+			acmeGrants++
+		}
+	}
+	if acmeGrants != 1 {
+		t.Errorf("acme has %d grants on portfolio growth, want 1", acmeGrants)
+	}
+
+	// only the member the listing doesn't name is fetched
+	if count := f.requestCount("issuers/acme"); count != 0 {
+		t.Errorf("issuer acme named by the listing was fetched %d times", count)
+	}
+}
+
+func TestPortfolioGrantsTruncatedAtIssuerPageCap(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.portfolios[0].members = []carta.Issuer{fakeIssuer("acme", "Acme Corp"), fakeIssuer("globex", "Globex"), fakeIssuer("initech", "Initech")}
+
+	result := runSync(t, f.connector(t, WithMaxPortfolioIssuerPages(1)))
+	assertSyncInvariants(t, result)
+
+	if result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeIssuer, "initech") {
+		t.Error("the member past the issuer page cap was granted")
+	}
+
+	var truncated bool
+	for _, annotation := range result.client.grantAnnotations[resourceTypePortfolio.Id+"/growth"] {
+		truncated = truncated || annotation.GetFields()["portfolio_issuers_truncated"].GetBoolValue()
+	}
+	if !truncated {
+		t.Error("the grants of the truncated portfolio carry no truncation annotation")
+	}
+
+	if annotations := result.client.grantAnnotations[resourceTypePortfolio.Id+"/seed"]; len(annotations) != 0 {
+		t.Errorf("the grants of portfolio seed carry annotations %v, want none", annotations)
+	}
+}
+
+func TestSkipEmptyPortfolios(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.portfolios = append(f.portfolios, fakePortfolio{portfolio: carta.Portfolio{Id: "empty", Name: "Empty"}})
+
+	result := runSync(t, f.connector(t, WithSkipEmptyPortfolios(true)))
+	assertSyncInvariants(t, result)
+
+	if result.hasResource(resourceTypePortfolio, "empty") {
+		t.Error("the empty portfolio was synced")
+	}
+
+	for _, id := range []string{"growth", "seed"} {
+		if !result.hasResource(resourceTypePortfolio, id) {
+			t.Errorf("portfolio %s with members was skipped", id)
+		}
+	}
+}
+
+func TestPortfolioGrantsPointAtSyncedIssuers(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default"},
+		{name: "lazy issuers", opts: []Option{WithLazyIssuers(true)}},
+		{name: "split funds", opts: []Option{WithSplitFunds(true)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixtureCarta(t)
+
+			// hooli is a member of a portfolio without being in the issuer listing
+			hooli := fakeIssuer("hooli", "Hooli")
+			f.hiddenIssuers = []carta.Issuer{hooli}
+			f.portfolios[1].members = append(f.portfolios[1].members, hooli)
+
+			result := runSync(t, f.connector(t, tc.opts...))
+			assertSyncInvariants(t, result)
+
+			var memberships int
+			for _, g := range result.grants {
+				principalType := g.Principal.Id.ResourceType
+				if g.Entitlement.Resource.Id.ResourceType != resourceTypePortfolio.Id || (principalType != resourceTypeIssuer.Id && principalType != resourceTypeFund.Id) {
+					continue
+				}
+				memberships++
+
+				if _, ok := result.resources[resourceKey(g.Principal.Id)]; !ok {
+					t.Errorf("membership grant %s points at issuer %s, which was not synced", g.Id, g.Principal.Id.Resource)
+				}
+			}
+
+			if want := 4; memberships != want {
+				t.Errorf("synced %d portfolio membership grants, want %d", memberships, want)
+			}
+
+			if !result.hasResource(resourceTypeIssuer, "hooli") {
+				t.Error("the portfolio member missing from the issuer listing was not synced")
+			}
+
+			if !result.hasGrant(resourceTypePortfolio, "seed", memberEntitlement, resourceTypeIssuer, "hooli") {
+				t.Error("the portfolio member missing from the issuer listing has no member grant")
+			}
+		})
+	}
+}
+
+func TestPortfolioMembersFromIssuerListingNotFetched(t *testing.T) {
+	f := newFixtureCarta(t)
+
+	result := runSync(t, f.connector(t))
+	assertSyncInvariants(t, result)
+
+	// members found in the issuer listing aren't fetched again
+	if count := f.requestCount("issuers/initech"); count != 0 {
+		t.Errorf("issuer initech from the issuer listing was fetched %d times", count)
+	}
+}
+
+func TestPortfolioGrantsStreamMemberPages(t *testing.T) {
+	f := newFixtureCarta(t)
+	ctx := testContext(t)
+
+	firm := carta.Issuer{BaseResource: carta.BaseResource{Id: "Sequoia"}, MemberType: "firm", AccessLevel: "viewer"}
+	f.portfolios[0].members = []carta.Issuer{fakeIssuer("acme", "Acme Corp"), fakeIssuer("globex", "Globex"), fakeIssuer("initech", "Initech"), firm}
+	f.portfolios[0].firms = []carta.InvestorFirm{fakeFirm("sequoia", "Sequoia"), fakeFirm("a16z", "Andreessen Horowitz")}
+
+	cartaConnector := f.connector(t)
+	portfolios := resourceSyncer(t, cartaConnector, resourceTypePortfolio)
+	resources, _, _, err := portfolios.List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	// the other listings run the way a sync runs them, the issuer listing walks the portfolio members
+	if err := cartaConnector.syncOptions.ensureListed(ctx); err != nil {
+		t.Fatalf("ensureListed() error = %v", err)
+	}
+	listedRequests := f.requestCount("portfolios/growth/issuers")
+
+	var growth *v2.Resource
+	for _, resource := range resources {
+		if resource.Id.Resource == "growth" {
+			growth = resource
+		}
+	}
+	if growth == nil {
+		t.Fatal("List() did not return portfolio growth")
+	}
+
+	// each call returns a single member page, the shared firms come last in a call of their own
+	var pages [][]string
+	token := ""
+	for calls := 0; ; calls++ {
+		if calls > 10 {
+			t.Fatal("the portfolio grants did not end")
+		}
+
+		grants, next, _, err := portfolios.Grants(ctx, growth, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("Grants() error = %v", err)
+		}
+
+		var page []string
+		for _, g := range grants {
+			entitlement := g.Entitlement.Id[strings.LastIndex(g.Entitlement.Id, ":")+1:]
+			page = append(page, entitlement+":"+g.Principal.Id.Resource)
+		}
+		pages = append(pages, page)
+
+		// the fixture pages members by two, the firm call requests no member page
+		wantRequests := calls + 1
+		if wantRequests > 2 {
+			wantRequests = 2
+		}
+		if got := f.requestCount("portfolios/growth/issuers") - listedRequests; got != wantRequests {
+			t.Errorf("after %d Grants() calls the member pages were requested %d times, want %d", calls+1, got, wantRequests)
+		}
+
+		if token = next; token == "" {
+			break
+		}
+	}
+
+	want := [][]string{
+		{"member:acme", "member:globex"},
+		// the firm resolves to the id the firm listing listed it with
+		{"viewer:sequoia", "member:initech"},
+		// the firm granted as a member isn't granted again
+		{"member:a16z"},
+	}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("Grants() pages = %v, want %v", pages, want)
+	}
+}
+
+func TestSplitFundsPortfolioMembersTypedByIssuerRecord(t *testing.T) {
+	f := newFakeCarta(t)
+
+	acme := fakeIssuer("acme", "Acme Corp")
+	growthFund := fakeIssuer("growth-fund", "Growth Fund I")
+	growthFund.Type = "fund"
+	f.issuers = []carta.Issuer{acme, growthFund}
+
+	// the portfolio listing tells the issuer types the other way round
+	listedAcme := acme
+	listedAcme.Type = "fund"
+	listedFund := growthFund
+	listedFund.Type = "company"
+	f.portfolios = []fakePortfolio{{
+		portfolio: carta.Portfolio{Id: "growth", Name: "Growth"},
+		members:   []carta.Issuer{listedAcme, listedFund},
+	}}
+
+	result := runSync(t, f.connector(t, WithSplitFunds(true)))
+	assertSyncInvariants(t, result)
+
+	if !result.hasResource(resourceTypeFund, "growth-fund") || !result.hasResource(resourceTypeIssuer, "acme") {
+		t.Fatal("funds and companies were not synced under their own resource types")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeFund, "growth-fund") {
+		t.Error("the fund has no member grant under the fund resource type")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "growth", memberEntitlement, resourceTypeIssuer, "acme") {
+		t.Error("the company has no member grant under the issuer resource type")
+	}
+}
+
+func TestSplitFundsUnresolvedMembersIgnoreListedType(t *testing.T) {
+	f := newFixtureCarta(t)
+
+	// the issuer record of hooli can't be read, its portfolio listing claims it's a fund
+	hooli := fakeIssuer("hooli", "Hooli")
+	hooli.Type = "fund"
+	f.portfolios[1].members = append(f.portfolios[1].members, hooli)
+	f.failures["issuers/hooli"] = http.StatusForbidden
+
+	result := runSync(t, f.connector(t, WithSplitFunds(true)))
+	assertSyncInvariants(t, result)
+
+	if result.hasResource(resourceTypeFund, "hooli") {
+		t.Error("the member without an issuer record was synced as a fund from the portfolio listing")
+	}
+
+	if !result.hasGrant(resourceTypePortfolio, "seed", memberEntitlement, resourceTypeIssuer, "hooli") {
+		t.Error("the member without an issuer record has no member grant under the issuer resource type")
+	}
+}
+
+func TestDuplicatePortfolioAcrossPagesListedOnce(t *testing.T) {
+	f := newFixtureCarta(t)
+	// the fixture pages portfolios by two, growth is listed again on the second page
+	f.portfolios = append(f.portfolios, f.portfolios[0], fakePortfolio{portfolio: carta.Portfolio{Id: "late", Name: "Late"}})
+
+	pages := listedPages(t, resourceSyncer(t, f.connector(t), resourceTypePortfolio))
+
+	want := [][]string{
+		{"growth", "seed"},
+		// listing the first page again isn't a duplicate of its first listing
+		{"growth", "seed"},
+		{"late"},
+	}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("List() pages = %v, want %v", pages, want)
+	}
+}
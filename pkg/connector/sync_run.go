@@ -0,0 +1,190 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"go.uber.org/zap"
+)
+
+// syncRun holds the state the syncers build up while listing resources during a sync. A connector
+// service keeps the connector across syncs, so the state is dropped once a sync starts listing a
+// resource type that was already listed, which only happens when a new sync started.
+//
+// The state only lives in memory. A sync resumed from a checkpoint by another connector process goes
+// on from where it stopped, without the state of the listings that ran before, so the grants relying
+// on it replay those listings first, see ensureListed. A listing resumed halfway only knows the pages
+// listed since: cycles through parent companies listed before are not detected, and ids repeated from
+// those pages are listed again, which the sync stores once.
+type syncRun struct {
+	mtx sync.Mutex
+	// started records the resource types whose listing started during the current sync.
+	started     map[string]struct{}
+	duplicates  map[string]*duplicateDetector
+	hierarchies map[string]*issuerHierarchy
+	portfolios  *portfolioHierarchy
+	access      *portfolioAccess
+	boards      *boardSeats
+	// resets drop the state kept outside the run, e.g. the resource cap count and client caches.
+	resets []func()
+	// relists list a resource type again, by resource type, to rebuild the state of its listing.
+	relists map[string]func(ctx context.Context) error
+	// relistMtx serializes the replays, so a listing is replayed once however many grants wait on it.
+	relistMtx sync.Mutex
+}
+
+func newSyncRun() *syncRun {
+	return &syncRun{
+		started:     make(map[string]struct{}),
+		duplicates:  make(map[string]*duplicateDetector),
+		hierarchies: make(map[string]*issuerHierarchy),
+		portfolios:  newPortfolioHierarchy(),
+		access:      newPortfolioAccess(),
+		boards:      newBoardSeats(),
+		relists:     make(map[string]func(ctx context.Context) error),
+	}
+}
+
+// onReset registers a reset of state kept outside the run.
+func (r *syncRun) onReset(reset func()) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.resets = append(r.resets, reset)
+}
+
+// onRelist registers how the listing of the resource type is replayed.
+func (r *syncRun) onRelist(resourceTypeID string, relist func(ctx context.Context) error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.relists[resourceTypeID] = relist
+}
+
+// ensureListed replays the listings of the resource types that didn't start during the current run,
+// which happens when the sync resumed past them, so the state they build up is there for the grants.
+func (r *syncRun) ensureListed(ctx context.Context, so syncOptions, resourceTypeIDs ...string) error {
+	r.relistMtx.Lock()
+	defer r.relistMtx.Unlock()
+
+	for _, resourceTypeID := range resourceTypeIDs {
+		r.mtx.Lock()
+		_, started := r.started[resourceTypeID]
+		relist := r.relists[resourceTypeID]
+		r.mtx.Unlock()
+
+		if started || relist == nil {
+			continue
+		}
+
+		so.logLevels.Logger(ctx, carta.LogComponentPagination).Debug(
+			"carta-connector: resource type not listed by this connector, listing it again to rebuild its state",
+			zap.String("resource_type", resourceTypeID),
+		)
+
+		if err := relist(ctx); err != nil {
+			return fmt.Errorf("carta-connector: failed to list %s again: %w", resourceTypeID, err)
+		}
+	}
+
+	return nil
+}
+
+// begin records that the listing of the resource type starts from its first page, starting a new
+// run when the resource type was already listed.
+func (r *syncRun) begin(ctx context.Context, so syncOptions, resourceTypeID string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.started[resourceTypeID]; ok {
+		so.logLevels.Logger(ctx, carta.LogComponentPagination).Debug(
+			"carta-connector: listing started over, dropping the state of the previous sync",
+		)
+
+		r.started = make(map[string]struct{})
+		r.duplicates = make(map[string]*duplicateDetector)
+		r.hierarchies = make(map[string]*issuerHierarchy)
+		r.portfolios = newPortfolioHierarchy()
+		r.access = newPortfolioAccess()
+		r.boards = newBoardSeats()
+		for _, reset := range r.resets {
+			reset()
+		}
+	}
+
+	r.started[resourceTypeID] = struct{}{}
+}
+
+// duplicateDetector returns the duplicate detector of the resource type for the current run.
+func (r *syncRun) duplicateDetector(resourceTypeID string) *duplicateDetector {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.duplicates[resourceTypeID] == nil {
+		r.duplicates[resourceTypeID] = newDuplicateDetector()
+	}
+
+	return r.duplicates[resourceTypeID]
+}
+
+// issuerHierarchy returns the issuer hierarchy of the resource type for the current run.
+func (r *syncRun) issuerHierarchy(resourceTypeID string) *issuerHierarchy {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.hierarchies[resourceTypeID] == nil {
+		r.hierarchies[resourceTypeID] = newIssuerHierarchy()
+	}
+
+	return r.hierarchies[resourceTypeID]
+}
+
+// portfolioHierarchy returns the portfolio hierarchy of the current run.
+func (r *syncRun) portfolioHierarchy() *portfolioHierarchy {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.portfolios
+}
+
+// portfolioAccess returns the portfolio access of the current run.
+func (r *syncRun) portfolioAccess() *portfolioAccess {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.access
+}
+
+// boardSeats returns the board seats of the current run.
+func (r *syncRun) boardSeats() *boardSeats {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.boards
+}
+
+// relister replays the listing of a top level syncer, the resources are dropped as the sync stored them already.
+func relister(resourceTypeID string, syncer connectorbuilder.ResourceSyncer) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		token := ""
+		for {
+			_, next, _, err := syncer.List(ctx, nil, &pagination.Token{Token: token})
+			if err != nil {
+				return err
+			}
+
+			if next == "" {
+				return nil
+			}
+
+			if next == token {
+				return fmt.Errorf("carta-connector: listing of %s returned its page token again", resourceTypeID)
+			}
+			token = next
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ConductorOne/baton-carta/pkg/carta"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+// fixtureResources is the number of resources newFixtureCarta serves.
+const fixtureResources = 12
+
+func TestResourceCapCountsEachSync(t *testing.T) {
+	f := newFixtureCarta(t)
+	cartaConnector := f.connector(t, WithMaxResources(fixtureResources))
+
+	for i := 0; i < 2; i++ {
+		result := runSync(t, cartaConnector)
+		if len(result.resources) != fixtureResources {
+			t.Fatalf("sync %d stored %d resources, want %d", i+1, len(result.resources), fixtureResources)
+		}
+	}
+}
+
+func TestSyncsDontShareListedState(t *testing.T) {
+	f := newFixtureCarta(t)
+	cartaConnector := f.connector(t)
+
+	first := runSync(t, cartaConnector)
+	assertSyncInvariants(t, first)
+
+	// the second sync sees the issuers on other pages, which isn't a duplicate of the first sync's listing
+	f.update(func(f *fakeCarta) {
+		f.issuers[0], f.issuers[2] = f.issuers[2], f.issuers[0]
+	})
+
+	second := runSync(t, cartaConnector)
+	assertSyncInvariants(t, second)
+
+	for key := range first.resources {
+		if _, ok := second.resources[key]; !ok {
+			t.Errorf("resource %s synced by the first sync is missing from the second", key)
+		}
+	}
+}
+
+func TestResumedSyncGrantsLikeFullSync(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		f := newFixtureCarta(t)
+		// a sub-portfolio and a firm contact granted the portfolios its firm is shared with
+		f.portfolios = append(f.portfolios, fakePortfolio{
+			portfolio: carta.Portfolio{Id: "growth-us", Name: "Growth US", ParentId: "growth"},
+			members:   []carta.Issuer{fakeIssuer("acme", "Acme Corp")},
+			firms:     []carta.InvestorFirm{fakeFirm("sequoia", "Sequoia")},
+		})
+		f.firmContacts["sequoia"][0].PortfolioIds = []string{"growth", "growth-us", "seed"}
+
+		full := runSync(t, f.connector(t, WithLazyIssuers(lazy)))
+		assertSyncInvariants(t, full)
+
+		want := make(map[string]struct{}, len(full.grants))
+		for _, g := range full.grants {
+			want[g.Id] = struct{}{}
+		}
+
+		// a sync resumed by another connector process after the listings only lists grants, the listings
+		// it replays don't count towards the resource cap
+		ctx := testContext(t)
+		resumed := f.connector(t, WithLazyIssuers(lazy), WithMaxResources(1))
+		syncers := make(map[string]connectorbuilder.ResourceSyncer)
+		for _, syncer := range resumed.ResourceSyncers(ctx) {
+			syncers[syncer.ResourceType(ctx).Id] = syncer
+		}
+
+		got := make(map[string]struct{})
+		for _, resource := range full.resources {
+			for _, g := range resourceGrants(t, syncers[resource.Id.ResourceType], resource) {
+				got[g.Id] = struct{}{}
+			}
+		}
+
+		for id := range want {
+			if _, ok := got[id]; !ok {
+				t.Errorf("lazy issuers %v: the resumed sync lost grant %s", lazy, id)
+			}
+		}
+
+		for id := range got {
+			if _, ok := want[id]; !ok {
+				t.Errorf("lazy issuers %v: the resumed sync made grant %s the full sync didn't", lazy, id)
+			}
+		}
+	}
+}
+
+func TestParallelListingsShareSyncRun(t *testing.T) {
+	f := newFixtureCarta(t)
+	f.latency = 5 * time.Millisecond
+	cartaConnector := f.connector(t, WithMaxConcurrentRequests(2), WithLazyIssuers(true))
+	ctx := testContext(t)
+
+	var syncers []connectorbuilder.ResourceSyncer
+	want := make(map[string][]string)
+	for _, syncer := range cartaConnector.ResourceSyncers(ctx) {
+		switch resourceTypeID := syncer.ResourceType(ctx).Id; resourceTypeID {
+		case resourceTypeIssuer.Id, resourceTypePortfolio.Id, resourceTypeInvestor.Id:
+			ids, err := listedIds(ctx, syncer)
+			if err != nil {
+				t.Fatalf("listing %s failed: %v", resourceTypeID, err)
+			}
+
+			syncers = append(syncers, syncer)
+			want[resourceTypeID] = ids
+		}
+	}
+
+	// the listings of the next syncs run at the same time, sharing the connector's sync run
+	const listings = 4
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	got := make(map[string][][]string)
+	for _, syncer := range syncers {
+		for i := 0; i < listings; i++ {
+			wg.Add(1)
+			go func(syncer connectorbuilder.ResourceSyncer) {
+				defer wg.Done()
+
+				resourceTypeID := syncer.ResourceType(ctx).Id
+				ids, err := listedIds(ctx, syncer)
+				if err != nil {
+					t.Errorf("listing %s failed: %v", resourceTypeID, err)
+					return
+				}
+
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				got[resourceTypeID] = append(got[resourceTypeID], ids)
+			}(syncer)
+		}
+	}
+	wg.Wait()
+
+	if maxInFlight := atomic.LoadInt32(&f.maxInFlight); maxInFlight != 2 {
+		t.Errorf("%d requests were in flight at the same time, want the cap of 2", maxInFlight)
+	}
+
+	for resourceTypeID, runs := range got {
+		for _, ids := range runs {
+			if !reflect.DeepEqual(ids, want[resourceTypeID]) {
+				t.Errorf("a parallel %s listing listed %v, want %v", resourceTypeID, ids, want[resourceTypeID])
+			}
+		}
+	}
+}
+
+// listedIds lists every page of the syncer's resources, returning the sorted ids listed.
+func listedIds(ctx context.Context, syncer connectorbuilder.ResourceSyncer) ([]string, error) {
+	var ids []string
+	token := ""
+	for calls := 0; calls < maxSyncCalls; calls++ {
+		resources, next, _, err := syncer.List(ctx, nil, &pagination.Token{Token: token})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range resources {
+			ids = append(ids, resource.Id.Resource)
+		}
+
+		if token = next; token == "" {
+			sort.Strings(ids)
+			return ids, nil
+		}
+	}
+
+	return nil, errors.New("the listing did not end")
+}
@@ -0,0 +1,489 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	connectorwrapperV1 "github.com/conductorone/baton-sdk/pb/c1/connector_wrapper/v1"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkSync "github.com/conductorone/baton-sdk/pkg/sync"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxSyncCalls bounds the connector calls of a test sync, so a pagination loop fails the test
+// instead of hanging it.
+const maxSyncCalls = 10000
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(ctxzap.ToContext(context.Background(), zap.NewNop()))
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+// syncClient serves the SDK syncer from the connector in process, recording every resource listed.
+type syncClient struct {
+	types.ConnectorServer
+
+	mtx    sync.Mutex
+	calls  int
+	listed map[string]int
+	// listAnnotations are the annotations returned with the resource pages.
+	listAnnotations []*structpb.Struct
+	// grantAnnotations are the annotations returned with the grant pages, keyed by resource.
+	grantAnnotations map[string][]*structpb.Struct
+}
+
+func (c *syncClient) call() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.calls++
+	if c.calls > maxSyncCalls {
+		return errors.New("sync made too many connector calls, pagination may not terminate")
+	}
+
+	return nil
+}
+
+func (c *syncClient) ListResourceTypes(ctx context.Context, in *v2.ResourceTypesServiceListResourceTypesRequest, _ ...grpc.CallOption) (*v2.ResourceTypesServiceListResourceTypesResponse, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+
+	return c.ConnectorServer.ListResourceTypes(ctx, in)
+}
+
+func (c *syncClient) ListResources(ctx context.Context, in *v2.ResourcesServiceListResourcesRequest, _ ...grpc.CallOption) (*v2.ResourcesServiceListResourcesResponse, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.ConnectorServer.ListResources(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, resource := range resp.List {
+		c.listed[resourceKey(resource.Id)]++
+	}
+
+	for _, a := range resp.Annotations {
+		st := &structpb.Struct{}
+		if a.MessageIs(st) && a.UnmarshalTo(st) == nil {
+			c.listAnnotations = append(c.listAnnotations, st)
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *syncClient) ListEntitlements(ctx context.Context, in *v2.EntitlementsServiceListEntitlementsRequest, _ ...grpc.CallOption) (*v2.EntitlementsServiceListEntitlementsResponse, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+
+	return c.ConnectorServer.ListEntitlements(ctx, in)
+}
+
+func (c *syncClient) ListGrants(ctx context.Context, in *v2.GrantsServiceListGrantsRequest, _ ...grpc.CallOption) (*v2.GrantsServiceListGrantsResponse, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.ConnectorServer.ListGrants(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, a := range resp.Annotations {
+		st := &structpb.Struct{}
+		if a.MessageIs(st) && a.UnmarshalTo(st) == nil {
+			key := resourceKey(in.Resource.Id)
+			c.grantAnnotations[key] = append(c.grantAnnotations[key], st)
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *syncClient) GetMetadata(ctx context.Context, in *v2.ConnectorServiceGetMetadataRequest, _ ...grpc.CallOption) (*v2.ConnectorServiceGetMetadataResponse, error) {
+	return c.ConnectorServer.GetMetadata(ctx, in)
+}
+
+func (c *syncClient) Validate(ctx context.Context, in *v2.ConnectorServiceValidateRequest, _ ...grpc.CallOption) (*v2.ConnectorServiceValidateResponse, error) {
+	return c.ConnectorServer.Validate(ctx, in)
+}
+
+func (c *syncClient) GetAsset(_ context.Context, _ *v2.AssetServiceGetAssetRequest, _ ...grpc.CallOption) (v2.AssetService_GetAssetClient, error) {
+	return nil, errors.New("assets are not synced")
+}
+
+// syncClientWrapper hands the syncer the in process client. Closing it leaves the connector open,
+// so a test can sync the same connector several times.
+type syncClientWrapper struct {
+	client *syncClient
+}
+
+func (w *syncClientWrapper) C(_ context.Context) (types.ConnectorClient, error) {
+	return w.client, nil
+}
+
+func (w *syncClientWrapper) Run(_ context.Context, _ *connectorwrapperV1.ServerConfig) error {
+	return nil
+}
+
+func (w *syncClientWrapper) Close() error {
+	return nil
+}
+
+// syncResult is what a sync stored.
+type syncResult struct {
+	resources    map[string]*v2.Resource
+	entitlements map[string]*v2.Entitlement
+	grants       []*v2.Grant
+	client       *syncClient
+}
+
+// runSync runs a full SDK sync of the connector into a new c1z file and reads back what was stored.
+func runSync(t *testing.T, cartaConnector *Carta) *syncResult {
+	t.Helper()
+
+	ctx := testContext(t)
+
+	server, err := connectorbuilder.NewConnector(ctx, cartaConnector)
+	if err != nil {
+		t.Fatalf("failed to create connector server: %v", err)
+	}
+
+	client := &syncClient{
+		ConnectorServer:  server,
+		listed:           make(map[string]int),
+		grantAnnotations: make(map[string][]*structpb.Struct),
+	}
+
+	path := filepath.Join(t.TempDir(), "sync.c1z")
+	store, err := dotc1z.NewC1ZFile(ctx, path)
+	if err != nil {
+		t.Fatalf("failed to create c1z: %v", err)
+	}
+
+	syncer := sdkSync.NewSyncer(store, &syncClientWrapper{client: client})
+	if err := syncer.Sync(ctx); err != nil {
+		_ = syncer.Close()
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if err := syncer.Close(); err != nil {
+		t.Fatalf("failed to close syncer: %v", err)
+	}
+
+	reader, err := dotc1z.NewC1ZFile(ctx, path)
+	if err != nil {
+		t.Fatalf("failed to open c1z: %v", err)
+	}
+	defer reader.Close()
+
+	result := &syncResult{
+		resources:    make(map[string]*v2.Resource),
+		entitlements: make(map[string]*v2.Entitlement),
+		client:       client,
+	}
+
+	pageToken := ""
+	for {
+		resp, err := reader.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatalf("failed to list stored resources: %v", err)
+		}
+		for _, resource := range resp.List {
+			result.resources[resourceKey(resource.Id)] = resource
+		}
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	for {
+		resp, err := reader.ListEntitlements(ctx, &v2.EntitlementsServiceListEntitlementsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatalf("failed to list stored entitlements: %v", err)
+		}
+		for _, entitlement := range resp.List {
+			result.entitlements[entitlement.Id] = entitlement
+		}
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	for {
+		resp, err := reader.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatalf("failed to list stored grants: %v", err)
+		}
+		result.grants = append(result.grants, resp.List...)
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	return result
+}
+
+// resourceSyncer returns the connector's syncer of the resource type, for tests calling it directly.
+func resourceSyncer(t *testing.T, cartaConnector *Carta, resourceType *v2.ResourceType) connectorbuilder.ResourceSyncer {
+	t.Helper()
+
+	ctx := testContext(t)
+	for _, syncer := range cartaConnector.ResourceSyncers(ctx) {
+		if syncer.ResourceType(ctx).Id == resourceType.Id {
+			return syncer
+		}
+	}
+
+	t.Fatalf("the connector has no %s syncer", resourceType.Id)
+
+	return nil
+}
+
+func resourceKey(id *v2.ResourceId) string {
+	return id.ResourceType + "/" + id.Resource
+}
+
+// hasResource reports whether a resource of the type and id was stored.
+func (r *syncResult) hasResource(resourceType *v2.ResourceType, id string) bool {
+	_, ok := r.resources[resourceKey(&v2.ResourceId{ResourceType: resourceType.Id, Resource: id})]
+	return ok
+}
+
+// hasGrant reports whether the principal was granted the entitlement on the resource.
+func (r *syncResult) hasGrant(resourceType *v2.ResourceType, resourceId string, entitlement string, principalType *v2.ResourceType, principalId string) bool {
+	return r.grant(resourceType, resourceId, entitlement, principalType, principalId) != nil
+}
+
+// grant returns the grant of the entitlement on the resource to the principal, nil when there is none.
+func (r *syncResult) grant(resourceType *v2.ResourceType, resourceId string, entitlement string, principalType *v2.ResourceType, principalId string) *v2.Grant {
+	entitlementId := resourceType.Id + ":" + resourceId + ":" + entitlement
+	for _, g := range r.grants {
+		if g.Entitlement.Id == entitlementId &&
+			g.Principal.Id.ResourceType == principalType.Id &&
+			g.Principal.Id.Resource == principalId {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// assertSyncInvariants checks what holds for any sync: resources are listed once, and every grant
+// points at a stored entitlement, resource and principal.
+func assertSyncInvariants(t *testing.T, result *syncResult) {
+	t.Helper()
+
+	for key, count := range result.client.listed {
+		if count > 1 {
+			t.Errorf("resource %s was listed %d times", key, count)
+		}
+	}
+
+	for _, g := range result.grants {
+		if _, ok := result.entitlements[g.Entitlement.Id]; !ok {
+			t.Errorf("grant %s references missing entitlement %s", g.Id, g.Entitlement.Id)
+		}
+		if _, ok := result.resources[resourceKey(g.Entitlement.Resource.Id)]; !ok {
+			t.Errorf("grant %s references missing resource %s", g.Id, resourceKey(g.Entitlement.Resource.Id))
+		}
+		if _, ok := result.resources[resourceKey(g.Principal.Id)]; !ok {
+			t.Errorf("grant %s references missing principal %s", g.Id, resourceKey(g.Principal.Id))
+		}
+	}
+}
+
+func TestSyncInvariants(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default"},
+		{name: "lazy issuers", opts: []Option{WithLazyIssuers(true)}},
+		{name: "split funds", opts: []Option{WithSplitFunds(true)}},
+		{name: "lazy issuers with split funds", opts: []Option{WithLazyIssuers(true), WithSplitFunds(true)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixtureCarta(t)
+			fund := fakeIssuer("growth-fund", "Growth Fund I")
+			fund.Type = "fund"
+			f.issuers = append(f.issuers, fund)
+			f.portfolios[0].members = append(f.portfolios[0].members, fund)
+
+			result := runSync(t, f.connector(t, tc.opts...))
+			assertSyncInvariants(t, result)
+			assertPortfolioMembersSynced(t, f, result)
+
+			for _, id := range []string{"bob", "carol"} {
+				if !result.hasGrant(resourceTypeIssuer, "acme", boardEntitlement, resourceTypeBoardMember, id) {
+					t.Errorf("board member %s has no board grant on acme", id)
+				}
+			}
+		})
+	}
+}
+
+// assertPortfolioMembersSynced checks that the sync stored every portfolio the fake serves, along with its
+// member issuers, whichever issuer resource type they're synced as, and their member grants.
+func assertPortfolioMembersSynced(t *testing.T, f *fakeCarta, result *syncResult) {
+	t.Helper()
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	for _, portfolio := range f.portfolios {
+		if !result.hasResource(resourceTypePortfolio, portfolio.portfolio.Id) {
+			t.Errorf("portfolio %s was not synced", portfolio.portfolio.Id)
+		}
+
+		for _, member := range portfolio.members {
+			var synced bool
+			for _, resourceType := range []*v2.ResourceType{resourceTypeIssuer, resourceTypeFund} {
+				if !result.hasResource(resourceType, member.Id) {
+					continue
+				}
+				synced = true
+
+				if !result.hasGrant(resourceTypePortfolio, portfolio.portfolio.Id, memberEntitlement, resourceType, member.Id) {
+					t.Errorf("member %s of portfolio %s has no member grant", member.Id, portfolio.portfolio.Id)
+				}
+			}
+
+			if !synced {
+				t.Errorf("member %s of portfolio %s was not synced", member.Id, portfolio.portfolio.Id)
+			}
+		}
+	}
+}
+
+func TestProfilesCarryCartaIds(t *testing.T) {
+	f := newFixtureCarta(t)
+
+	result := runSync(t, f.connector(t, WithResourceIdPrefix("carta:")))
+	assertSyncInvariants(t, result)
+
+	for _, tc := range []struct {
+		resourceType *v2.ResourceType
+		cartaId      string
+		profileKey   string
+	}{
+		{resourceTypeIssuer, "acme", "issuer_id"},
+		{resourceTypeInvestor, "sequoia", "investor_id"},
+		{resourceTypePortfolio, "growth", "portfolio_id"},
+	} {
+		resource, ok := result.resources[resourceKey(&v2.ResourceId{ResourceType: tc.resourceType.Id, Resource: "carta:" + tc.cartaId})]
+		if !ok {
+			t.Errorf("%s %s was not synced", tc.resourceType.Id, tc.cartaId)
+			continue
+		}
+
+		var profile *structpb.Struct
+		if tc.resourceType == resourceTypeIssuer {
+			trait, err := rs.GetUserTrait(resource)
+			if err != nil {
+				t.Fatalf("failed to read the user trait of %s: %v", tc.cartaId, err)
+			}
+			profile = trait.Profile
+		} else {
+			trait, err := rs.GetGroupTrait(resource)
+			if err != nil {
+				t.Fatalf("failed to read the group trait of %s: %v", tc.cartaId, err)
+			}
+			profile = trait.Profile
+		}
+
+		if id, _ := rs.GetProfileStringValue(profile, tc.profileKey); id != tc.cartaId {
+			t.Errorf("%s %s has profile %s %q, want the Carta id without the prefix", tc.resourceType.Id, tc.cartaId, tc.profileKey, id)
+		}
+
+		annos := annotations.Annotations(resource.Annotations)
+		if annos.Contains(&v2.V1Identifier{}) {
+			t.Errorf("%s %s carries a V1Identifier annotation", tc.resourceType.Id, tc.cartaId)
+		}
+	}
+}
+
+// listedPages lists every page of the syncer's resources, returning the resource ids of each page in order.
+// The first page is listed twice, the way the sync lists a page again when it retries it.
+func listedPages(t *testing.T, syncer connectorbuilder.ResourceSyncer) [][]string {
+	t.Helper()
+
+	ctx := testContext(t)
+	var pages [][]string
+	token := ""
+	for calls := 0; ; calls++ {
+		if calls > 10 {
+			t.Fatal("the listing did not end")
+		}
+
+		resources, next, _, err := syncer.List(ctx, nil, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+
+		page := make([]string, 0, len(resources))
+		for _, resource := range resources {
+			page = append(page, resource.Id.Resource)
+		}
+		pages = append(pages, page)
+
+		if calls == 0 {
+			continue
+		}
+
+		if token = next; token == "" {
+			return pages
+		}
+	}
+}
+
+// resourceGrants returns every page of the grants of the resource, as the syncer lists them.
+func resourceGrants(t *testing.T, syncer connectorbuilder.ResourceSyncer, resource *v2.Resource) []*v2.Grant {
+	t.Helper()
+
+	ctx := testContext(t)
+	var rv []*v2.Grant
+	token := ""
+	for calls := 0; ; calls++ {
+		if calls > maxSyncCalls {
+			t.Fatalf("the grants of %s did not end", resourceKey(resource.Id))
+		}
+
+		grants, next, _, err := syncer.Grants(ctx, resource, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("Grants() of %s error = %v", resourceKey(resource.Id), err)
+		}
+		rv = append(rv, grants...)
+
+		if token = next; token == "" {
+			return rv
+		}
+	}
+}